@@ -0,0 +1,296 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/pedroegsilva/cw-test/checkpoint"
+	"github.com/pedroegsilva/cw-test/filter"
+	"github.com/pedroegsilva/cw-test/index"
+	"github.com/pedroegsilva/cw-test/parser"
+	"github.com/pedroegsilva/cw-test/reports"
+)
+
+type Options struct {
+	// Context stops the scans once it is done, the files and segments not
+	// scanned by then end with its error. context.Background when nil.
+	Context context.Context
+	// Workers is the number of files or segments parsed at the same time.
+	// Defaults to the number of CPUs.
+	Workers     int
+	ErrorPolicy parser.ErrorPolicy
+	// CheckpointDir enables resuming the scan of each file from the last
+	// checkpoint saved on this directory.
+	CheckpointDir string
+	// TagOptions are used to tag the games, parser.DefaultTagOptions when empty.
+	TagOptions parser.TagOptions
+	// Scoring are the rules used to score the players, parser.DefaultScoringRules when empty.
+	Scoring parser.ScoringRules
+	// Exclude leaves the games with any of these tags out of the summaries.
+	Exclude parser.GameTag
+	// Dialect forces the grammar of the logs, nil detects it on each game.
+	Dialect *parser.Dialect
+	// Awards are the rules of the awards of each game and of the summaries,
+	// parser.DefaultAwardRules when nil.
+	Awards []parser.AwardRule
+	// Where leaves the games and players it does not match out of the
+	// summaries, nil matches every game.
+	Where *filter.Filter
+}
+
+// selects tells if the game goes on the summaries.
+func (opts *Options) selects(game *parser.Game) bool {
+	return !game.Tags.Any(opts.Exclude) && (opts.Where == nil || opts.Where.MatchGame(game))
+}
+
+// KeepPlayers selects the players of the game matched by where, nil keeps
+// every player.
+func KeepPlayers(where *filter.Filter, game *parser.Game) reports.PlayerFilter {
+	if where == nil {
+		return nil
+	}
+	return func(info *parser.PlayersInfo) bool { return where.MatchPlayer(game, info) }
+}
+
+// GameResult is a game or the error that dropped it, in log order.
+type GameResult struct {
+	Game *parser.Game
+	Err  error
+}
+
+type FileResult struct {
+	Path string
+	// FirstGame is the amount of games of the file emitted by previous runs.
+	FirstGame int
+	Games     []GameResult
+	Summary   *reports.Summary
+	// Err is set when the file could not be read or the scan was aborted.
+	Err error
+	// Checkpoint is the progress to be saved once the games are handled.
+	Checkpoint     *checkpoint.Checkpoint
+	checkpointPath string
+}
+
+// SaveCheckpoint persists the progress of the scan of the file. It should
+// only be called after the games are handled so they are not lost.
+func (fr *FileResult) SaveCheckpoint() error {
+	if fr.Checkpoint == nil {
+		return nil
+	}
+	return fr.Checkpoint.Save(fr.checkpointPath)
+}
+
+// ProcessFiles parses the files concurrently, each one with its own
+// GameScanner, and calls handle with the results in the same order as paths.
+// handle is never called concurrently, so it may aggregate without locking.
+func ProcessFiles(paths []string, opts Options, handle func(*FileResult)) {
+	runOrdered(len(paths), opts.Workers, func(i int) *FileResult {
+		return ProcessFile(paths[i], opts)
+	}, handle)
+}
+
+// runOrdered runs work for every index from 0 to n-1 on a pool of workers and
+// calls handle with the results in index order.
+func runOrdered[T any](n int, workers int, work func(int) T, handle func(T)) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([]chan T, n)
+	for i := range results {
+		results[i] = make(chan T, 1)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] <- work(i)
+			}
+		}()
+	}
+
+	go func() {
+		for i := 0; i < n; i++ {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	for i := range results {
+		handle(<-results[i])
+	}
+	wg.Wait()
+}
+
+// ProcessFile parses every game of a single file.
+func ProcessFile(path string, opts Options) *FileResult {
+	result := &FileResult{
+		Path:    path,
+		Summary: reports.NewSummary(),
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer file.Close()
+
+	gameScanner := newGameScanner(file, opts)
+	var identity checkpoint.FileIdentity
+	if opts.CheckpointDir != "" {
+		// a line still being written is read whole on the next run
+		gameScanner.HoldPartialLine = true
+		result.checkpointPath = checkpoint.PathFor(opts.CheckpointDir, path)
+		identity, err = checkpoint.Identify(file)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.FirstGame, err = resume(file, gameScanner, result.checkpointPath)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	games, last, err := scanGames(gameScanner)
+	// without a checkpoint the game left open is never resumed, it is ended
+	// as the sequential scan ends a game without ShutdownGame
+	if opts.CheckpointDir == "" && last != nil {
+		last.EndingReason = "SERVER_UNEXPECTED_SHUTDOWN"
+		games = append(games, GameResult{Game: last})
+	}
+	result.Games, result.Err = games, err
+	if opts.CheckpointDir != "" && result.Err == nil {
+		result.Checkpoint = &checkpoint.Checkpoint{
+			File:  identity,
+			Games: result.FirstGame + len(result.Games),
+			State: gameScanner.State(),
+		}
+	}
+	for _, gr := range result.Games {
+		if gr.Err == nil && opts.selects(gr.Game) {
+			result.Summary.AddGame(gr.Game, KeepPlayers(opts.Where, gr.Game))
+		}
+	}
+
+	return result
+}
+
+// SegmentResult holds the games parsed from a segment of an indexed log.
+// Number is the position of the game on the log, starting at 1.
+type SegmentResult struct {
+	Number  int
+	Segment index.Segment
+	Games   []GameResult
+	Summary *reports.Summary
+	Err     error
+}
+
+// ProcessSegments parses the games of an indexed log concurrently, each
+// segment with its own GameScanner. numbers selects the games to parse by
+// their position on the log, all games are parsed when it is empty. handle is
+// called with the results in the order of the log.
+func ProcessSegments(idx *index.Index, numbers []int, opts Options, handle func(*SegmentResult)) error {
+	file, err := os.Open(idx.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if len(numbers) == 0 {
+		numbers = make([]int, len(idx.Segments))
+		for i := range numbers {
+			numbers[i] = i + 1
+		}
+	}
+
+	runOrdered(len(numbers), opts.Workers, func(i int) *SegmentResult {
+		return processSegment(file, idx, numbers[i], opts)
+	}, handle)
+	return nil
+}
+
+func processSegment(file *os.File, idx *index.Index, number int, opts Options) *SegmentResult {
+	result := &SegmentResult{
+		Number:  number,
+		Summary: reports.NewSummary(),
+	}
+	if number < 1 || number > len(idx.Segments) {
+		result.Err = fmt.Errorf("game %d not found, the log has %d games", number, len(idx.Segments))
+		return result
+	}
+	seg := idx.Segments[number-1]
+	result.Segment = seg
+
+	section := io.NewSectionReader(file, seg.Start, seg.End-seg.Start)
+	games, last, err := scanGames(newGameScanner(section, opts))
+	// a game without ShutdownGame is ended by the next InitGame or by the end
+	// of the log, as on the sequential scan without checkpoints
+	if last != nil && seg.Ending != index.SEShutdown {
+		last.EndingReason = "SERVER_UNEXPECTED_SHUTDOWN"
+		games = append(games, GameResult{Game: last})
+	}
+	result.Games = games
+	result.Err = err
+	for _, gr := range result.Games {
+		if gr.Err == nil && opts.selects(gr.Game) {
+			result.Summary.AddGame(gr.Game, KeepPlayers(opts.Where, gr.Game))
+		}
+	}
+
+	return result
+}
+
+// resume restores the scanner from the checkpoint of the file, when there is
+// one taken from the same file, and returns the amount of games already
+// emitted. Rotated or truncated files are scanned from the beginning.
+func resume(file *os.File, gameScanner *parser.GameScanner, checkpointPath string) (int, error) {
+	cp, err := checkpoint.Load(checkpointPath)
+	if err != nil || cp == nil {
+		return 0, err
+	}
+	matches, err := cp.Matches(file)
+	if err != nil || !matches {
+		return 0, err
+	}
+	if _, err := file.Seek(cp.State.Offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	gameScanner.Restore(cp.State)
+	return cp.Games, nil
+}
+
+func newGameScanner(reader io.Reader, opts Options) *parser.GameScanner {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return parser.NewGameScanner(ctx, reader, parser.ScannerOptions{
+		ErrorPolicy: opts.ErrorPolicy,
+		TagOptions:  opts.TagOptions,
+		Scoring:     opts.Scoring,
+		Awards:      opts.Awards,
+		Dialect:     opts.Dialect,
+	})
+}
+
+// scanGames reads every game of the scanner. The game still open at the end
+// of the input is returned apart since it may not be complete.
+func scanGames(gameScanner *parser.GameScanner) ([]GameResult, *parser.Game, error) {
+	var games []GameResult
+	game, ok, err := gameScanner.GetGame()
+	for ; ok; game, ok, err = gameScanner.GetGame() {
+		games = append(games, GameResult{Game: game, Err: err})
+	}
+	return games, game, err
+}
@@ -0,0 +1,228 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pedroegsilva/cw-test/index"
+	"github.com/pedroegsilva/cw-test/reports"
+	"github.com/rs/zerolog"
+)
+
+const sampleLog = "../input/qgames.log"
+
+func TestMain(m *testing.M) {
+	// the sample log has unknown headers that would flood the output
+	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+	os.Exit(m.Run())
+}
+
+// writeLogs creates count log files, each one with the sample log repeated
+// copies times.
+func writeLogs(tb testing.TB, count int, copies int) []string {
+	tb.Helper()
+	data, err := os.ReadFile(sampleLog)
+	if err != nil {
+		tb.Fatalf("could not read sample log: %s", err)
+	}
+	content := strings.Repeat(string(data)+"\n", copies)
+
+	dir := tb.TempDir()
+	paths := make([]string, count)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("server-%d.log", i))
+		if err := os.WriteFile(paths[i], []byte(content), 0o644); err != nil {
+			tb.Fatalf("could not write log: %s", err)
+		}
+	}
+	return paths
+}
+
+func TestProcessFilesOrder(t *testing.T) {
+	paths := writeLogs(t, 8, 1)
+	paths = append(paths, filepath.Join(t.TempDir(), "missing.log"))
+
+	sequential := reports.NewSummary()
+	for _, p := range paths[:len(paths)-1] {
+		sequential.Merge(ProcessFile(p, Options{}).Summary)
+	}
+
+	var order []string
+	parallel := reports.NewSummary()
+	ProcessFiles(paths, Options{Workers: 4}, func(result *FileResult) {
+		order = append(order, result.Path)
+		parallel.Merge(result.Summary)
+	})
+
+	for i := range paths {
+		if order[i] != paths[i] {
+			t.Fatalf("Expected results in input order, got %v", order)
+		}
+	}
+
+	if parallel.Games != sequential.Games || parallel.TotalKills != sequential.TotalKills {
+		t.Errorf("Expected %d games and %d kills, got %d games and %d kills",
+			sequential.Games, sequential.TotalKills, parallel.Games, parallel.TotalKills)
+	}
+	sp, pp := sequential.Players(), parallel.Players()
+	if len(sp) != len(pp) {
+		t.Fatalf("Expected %d players, got %d", len(sp), len(pp))
+	}
+	for i := range sp {
+		if *sp[i] != *pp[i] {
+			t.Errorf("Expected %+v, got %+v", sp[i], pp[i])
+		}
+	}
+}
+
+func TestProcessFileGames(t *testing.T) {
+	result := ProcessFile(sampleLog, Options{})
+	if result.Err != nil {
+		t.Fatalf("Unexpected error: %s", result.Err)
+	}
+	// the last game of the sample is ended with ShutdownGame
+	if len(result.Games) != 21 {
+		t.Errorf("Expected 21 games, got %d", len(result.Games))
+	}
+
+	result = ProcessFile("missing.log", Options{})
+	if result.Err == nil {
+		t.Errorf("Expected error for missing file")
+	}
+}
+
+func benchmarkProcessFiles(b *testing.B, workers int) {
+	paths := writeLogs(b, 12, 20)
+	var size int64
+	for _, p := range paths {
+		info, _ := os.Stat(p)
+		size += info.Size()
+	}
+	b.SetBytes(size)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ProcessFiles(paths, Options{Workers: workers}, func(*FileResult) {})
+	}
+}
+
+func BenchmarkProcessFilesSequential(b *testing.B) {
+	benchmarkProcessFiles(b, 1)
+}
+
+func BenchmarkProcessFilesParallel4(b *testing.B) {
+	benchmarkProcessFiles(b, 4)
+}
+
+func BenchmarkProcessFilesParallelNumCPU(b *testing.B) {
+	benchmarkProcessFiles(b, 0)
+}
+
+func TestProcessSegments(t *testing.T) {
+	path := writeLogs(t, 1, 2)[0]
+	idx, err := index.Build(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	sequential := ProcessFile(path, Options{})
+	var games []GameResult
+	var numbers []int
+	err = ProcessSegments(idx, nil, Options{Workers: 4}, func(result *SegmentResult) {
+		numbers = append(numbers, result.Number)
+		games = append(games, result.Games...)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for i := range numbers {
+		if numbers[i] != i+1 {
+			t.Fatalf("Expected segments in log order, got %v", numbers)
+		}
+	}
+	if len(games) != len(sequential.Games) {
+		t.Fatalf("Expected %d games, got %d", len(sequential.Games), len(games))
+	}
+	for i, gr := range games {
+		sg := sequential.Games[i].Game
+		if gr.Game.TotalKills != sg.TotalKills || gr.Game.EndingReason != sg.EndingReason || len(gr.Game.PlayersInfoById) != len(sg.PlayersInfoById) {
+			t.Errorf("Expected game %d to match the sequential scan", i+1)
+		}
+	}
+
+	var single []*SegmentResult
+	ProcessSegments(idx, []int{3, 100}, Options{}, func(result *SegmentResult) {
+		single = append(single, result)
+	})
+	if single[0].Number != 3 || len(single[0].Games) != 1 || single[0].Games[0].Game.EndingReason != "SERVER_UNEXPECTED_SHUTDOWN" {
+		t.Errorf("Expected only game 3, got %+v", single[0])
+	}
+	if single[1].Err == nil {
+		t.Errorf("Expected error for missing game")
+	}
+}
+
+func TestProcessFilesCanceled(t *testing.T) {
+	paths := writeLogs(t, 2, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ProcessFiles(paths, Options{Context: ctx}, func(result *FileResult) {
+		if len(result.Games) != 0 || !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("Expected %v without games, but got %d games and %v", context.Canceled, len(result.Games), result.Err)
+		}
+	})
+}
+
+func TestProcessFileOpenGame(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "open.log")
+	content := `  0:00 InitGame: \mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:02 Kill: 1022 2 22: <world> killed Isgalamido by MOD_FALLING
+  0:03 ShutdownGame:
+  0:04 InitGame: \mapname\q3dm6
+  0:05 ClientConnect: 2
+  0:05 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:06 Kill: 1022 2 22: <world> killed Isgalamido by MOD_FALLING
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write log: %s", err)
+	}
+
+	// without checkpoints the game left open is ended
+	result := ProcessFile(path, Options{})
+	if result.Err != nil {
+		t.Fatalf("Unexpected error: %s", result.Err)
+	}
+	if len(result.Games) != 2 || result.Games[1].Game.EndingReason != "SERVER_UNEXPECTED_SHUTDOWN" || result.Games[1].Game.TotalKills != 1 {
+		t.Errorf("Expected the open game to end, got %+v", result.Games)
+	}
+	if result.Summary.Games != 2 {
+		t.Errorf("Expected 2 games on the summary, got %d", result.Summary.Games)
+	}
+
+	idx, err := index.Build(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var segments []GameResult
+	ProcessSegments(idx, nil, Options{}, func(result *SegmentResult) {
+		segments = append(segments, result.Games...)
+	})
+	if len(segments) != 2 || segments[1].Game.EndingReason != "SERVER_UNEXPECTED_SHUTDOWN" {
+		t.Errorf("Expected the segments to end the open game, got %+v", segments)
+	}
+
+	// with checkpoints it is resumed on the next run
+	result = ProcessFile(path, Options{CheckpointDir: t.TempDir()})
+	if len(result.Games) != 1 {
+		t.Errorf("Expected only the ended game, got %d games", len(result.Games))
+	}
+}
@@ -0,0 +1,121 @@
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+// headSize is the amount of bytes from the beginning of the file used to
+// recognize it after a rotation or truncation.
+const headSize = 1024
+
+// FileIdentity recognizes a log file. HeadHash is the hash of the first
+// HeadSize bytes of the file.
+type FileIdentity struct {
+	Path     string
+	HeadSize int64
+	HeadHash string
+}
+
+// Checkpoint is the progress of the scan of a log file. Games is the amount
+// of games already emitted, so a resumed scan keeps their numbering.
+type Checkpoint struct {
+	File  FileIdentity
+	Games int
+	State parser.ScannerState
+}
+
+// Identify computes the identity of the file with its current content.
+func Identify(file *os.File) (FileIdentity, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return FileIdentity{}, err
+	}
+	size := info.Size()
+	if size > headSize {
+		size = headSize
+	}
+	hash, err := hashHead(file, size)
+	if err != nil {
+		return FileIdentity{}, err
+	}
+	return FileIdentity{
+		Path:     file.Name(),
+		HeadSize: size,
+		HeadHash: hash,
+	}, nil
+}
+
+// Matches tells if the checkpoint was taken from file and if the file still
+// has every byte that was already scanned.
+func (c *Checkpoint) Matches(file *os.File) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() < c.State.Offset || info.Size() < c.File.HeadSize {
+		return false, nil
+	}
+	hash, err := hashHead(file, c.File.HeadSize)
+	if err != nil {
+		return false, err
+	}
+	return hash == c.File.HeadHash, nil
+}
+
+func hashHead(file *os.File, size int64) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(file, 0, size)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// PathFor returns the checkpoint file of a log inside dir.
+func PathFor(dir string, logPath string) string {
+	abs, err := filepath.Abs(logPath)
+	if err != nil {
+		abs = logPath
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// Load reads a checkpoint, it returns nil without error when there is none.
+func Load(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &Checkpoint{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Save writes the checkpoint replacing the previous one atomically.
+func (c *Checkpoint) Save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
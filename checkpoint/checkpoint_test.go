@@ -0,0 +1,69 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+func TestCheckpointMatches(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "games.log")
+	content := strings.Repeat("  0:00 ClientConnect: 2\n", 100)
+	if err := os.WriteFile(logPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write log: %s", err)
+	}
+
+	file, err := os.Open(logPath)
+	if err != nil {
+		t.Fatalf("could not open log: %s", err)
+	}
+	identity, err := Identify(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	cpPath := PathFor(filepath.Join(dir, "checkpoints"), logPath)
+	cp := &Checkpoint{File: identity, Games: 2, State: parser.ScannerState{Offset: int64(len(content))}}
+	if err := cp.Save(cpPath); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	loaded, err := Load(cpPath)
+	if err != nil || loaded == nil || loaded.Games != 2 {
+		t.Fatalf("Expected saved checkpoint, got %+v err %v", loaded, err)
+	}
+
+	tests := map[string]struct {
+		content  string
+		expected bool
+	}{
+		"Appended":  {content: content + "  0:00 ClientConnect: 3\n", expected: true},
+		"Truncated": {content: content[:100], expected: false},
+		"Rotated":   {content: strings.Repeat("  0:00 ClientConnect: 3\n", 100), expected: false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := os.WriteFile(logPath, []byte(test.content), 0o644); err != nil {
+				t.Fatalf("could not write log: %s", err)
+			}
+			file, err := os.Open(logPath)
+			if err != nil {
+				t.Fatalf("could not open log: %s", err)
+			}
+			defer file.Close()
+			matches, err := loaded.Matches(file)
+			if err != nil || matches != test.expected {
+				t.Errorf("Expected %v, got %v err %v", test.expected, matches, err)
+			}
+		})
+	}
+
+	missing, err := Load(filepath.Join(dir, "missing.json"))
+	if missing != nil || err != nil {
+		t.Errorf("Expected no checkpoint, got %+v err %v", missing, err)
+	}
+}
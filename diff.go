@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/pedroegsilva/cw-test/batch"
+	"github.com/pedroegsilva/cw-test/parser"
+	"github.com/pedroegsilva/cw-test/reports"
+	"github.com/rs/zerolog/log"
+)
+
+// runDiff compares the reports of two sets of files, such as the output of
+// two versions of the parser or the logs of two servers of the same match.
+// It exits with 1 when they differ, like diff.
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	var pathsA, pathsB pathList
+	fs.Var(&pathsA, "a", "log file or json reports of the first side, can be repeated")
+	fs.Var(&pathsB, "b", "log file or json reports of the second side, can be repeated")
+	mode := fs.String("mode", "lenient", "error policy of the log files: strict, lenient or recover")
+	readScoring := scoringFlag(fs)
+	readDialect := dialectFlag(fs)
+	fs.Parse(args)
+
+	if len(pathsA) == 0 || len(pathsB) == 0 {
+		fs.Usage()
+		return 2
+	}
+	policy, err := parser.ParseErrorPolicy(*mode)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	scoring, err := readScoring()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	dialect, err := readDialect()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+
+	opts := batch.Options{ErrorPolicy: policy, Scoring: scoring, Dialect: dialect}
+	a, errA := loadReports(pathsA, opts)
+	b, errB := loadReports(pathsB, opts)
+	if errA != nil || errB != nil {
+		return 2
+	}
+
+	diff := reports.DiffReports(a, b)
+	if os.Getenv("OUT_HUMAN") != "" {
+		reports.PrintHumanReadableDiff(diff)
+	} else {
+		reports.PrintDiffJson(diff)
+	}
+	if !diff.Empty() {
+		return 1
+	}
+	return 0
+}
+
+// loadReports reads the reports of the files of a side of the diff. Files
+// starting with { have the json reports printed by the main command, the
+// others are parsed as logs. The reports of each file have its position as
+// their source, so the nth file of a side is compared with the nth of the
+// other.
+func loadReports(paths []string, opts batch.Options) ([]*reports.Report, error) {
+	var reps []*reports.Report
+	for pos, path := range paths {
+		source := strconv.Itoa(pos + 1)
+		isJson, err := isJsonFile(path)
+		if err != nil {
+			log.Error().Msg(fmt.Sprintf("could not read %s: %s", path, err))
+			return nil, err
+		}
+		if isJson {
+			file, err := os.Open(path)
+			if err != nil {
+				log.Error().Msg(fmt.Sprintf("could not read %s: %s", path, err))
+				return nil, err
+			}
+			fileReports, err := reports.ReadReports(file)
+			file.Close()
+			if err != nil {
+				log.Error().Msg(fmt.Sprintf("could not read the reports of %s: %s", path, err))
+				return nil, err
+			}
+			for _, report := range fileReports {
+				report.Source = source
+			}
+			reps = append(reps, fileReports...)
+			continue
+		}
+
+		result := batch.ProcessFile(path, opts)
+		for idx, gr := range result.Games {
+			name := gameName(result.Path, result.FirstGame+idx+1, len(paths) > 1)
+			if gr.Err != nil {
+				log.Error().Msg(fmt.Sprintf("error on %s: %s", name, gr.Err))
+				continue
+			}
+			report := reports.NewReport(gr.Game, name)
+			report.Source = source
+			reps = append(reps, report)
+		}
+		if result.Err != nil {
+			log.Error().Msg(fmt.Sprintf("aborting %s: %s", result.Path, result.Err))
+			return nil, result.Err
+		}
+	}
+	return reps, nil
+}
+
+func isJsonFile(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			// empty files are logs without games
+			return false, nil
+		}
+		if r != ' ' && r != '\t' && r != '\n' && r != '\r' {
+			return r == '{', nil
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/pedroegsilva/cw-test/batch"
+	"github.com/pedroegsilva/cw-test/export"
+	"github.com/pedroegsilva/cw-test/parser"
+	"github.com/rs/zerolog/log"
+)
+
+// runExport writes the games of the logs on the given format. Only sql is
+// supported, the dump can be loaded into SQLite with `sqlite3 games.db < dump.sql`.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var inputPaths pathList
+	fs.Var(&inputPaths, "i", "full path of the log file, can be repeated. Extra arguments are also read as log files")
+	format := fs.String("format", "sql", "export format, only sql is supported")
+	output := fs.String("o", "", "output file, defaults to stdout")
+	mode := fs.String("mode", "lenient", "error policy: strict, lenient or recover")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of log files parsed concurrently")
+	readScoring := scoringFlag(fs)
+	readAwards := awardsFlag(fs)
+	readDialect := dialectFlag(fs)
+	readWhere := whereFlag(fs)
+	fs.Parse(args)
+	inputPaths = append(inputPaths, fs.Args()...)
+
+	if len(inputPaths) == 0 {
+		fs.Usage()
+		return 2
+	}
+	if *format != "sql" {
+		log.Error().Msg(fmt.Sprintf("unsupported export format: %s", *format))
+		return 2
+	}
+	policy, err := parser.ParseErrorPolicy(*mode)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+
+	scoring, err := readScoring()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	dialect, err := readDialect()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	awardRules, err := readAwards()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	where, err := readWhere()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Error().Msg(fmt.Sprintf("could not create output: %s", err))
+			return 2
+		}
+		defer file.Close()
+		out = file
+	}
+
+	writer := export.NewSQLWriter(out)
+	code := 0
+	batch.ProcessFiles(inputPaths, batch.Options{Workers: *workers, ErrorPolicy: policy, Scoring: scoring, Dialect: dialect, Awards: awardRules}, func(result *batch.FileResult) {
+		for idx, gr := range result.Games {
+			name := gameName(result.Path, idx+1, len(inputPaths) > 1)
+			if gr.Err != nil {
+				log.Error().Msg(fmt.Sprintf("error on %s: %s", name, gr.Err))
+				continue
+			}
+			// the games matched are exported whole, with every player
+			if where != nil && !where.MatchGame(gr.Game) {
+				continue
+			}
+			if err := writer.Write(export.NamedGame{Name: name, Game: gr.Game}); err != nil {
+				log.Error().Msg(fmt.Sprintf("could not export %s: %s", name, err))
+				code = 1
+			}
+		}
+		if result.Err != nil {
+			log.Error().Msg(fmt.Sprintf("aborting %s: %s", result.Path, result.Err))
+			code = 1
+		}
+	})
+	if err := writer.Close(); err != nil {
+		log.Error().Msg(fmt.Sprintf("could not export: %s", err))
+		code = 1
+	}
+	return code
+}
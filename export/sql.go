@@ -0,0 +1,244 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+// NamedGame is a game along with the name it has on the reports.
+type NamedGame struct {
+	Name string
+	Game *parser.Game
+}
+
+const schema = `CREATE TABLE IF NOT EXISTS games (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  map TEXT,
+  gametype INTEGER,
+  start_seconds INTEGER,
+  end_seconds INTEGER,
+  ending_reason TEXT,
+  ending_condition TEXT NOT NULL,
+  winner_id INTEGER REFERENCES players(id),
+  winning_team TEXT,
+  total_kills INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS players (
+  id INTEGER PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS player_game_stats (
+  game_id TEXT NOT NULL REFERENCES games(id),
+  seq INTEGER NOT NULL,
+  player_id INTEGER NOT NULL REFERENCES players(id),
+  client_id INTEGER NOT NULL,
+  disconnected INTEGER NOT NULL,
+  placement INTEGER,
+  score INTEGER NOT NULL,
+  kills INTEGER NOT NULL,
+  deaths INTEGER NOT NULL,
+  suicides INTEGER NOT NULL,
+  PRIMARY KEY (game_id, seq)
+);
+CREATE TABLE IF NOT EXISTS kills (
+  game_id TEXT NOT NULL REFERENCES games(id),
+  seq INTEGER NOT NULL,
+  seconds INTEGER,
+  killer_id INTEGER REFERENCES players(id),
+  victim_id INTEGER REFERENCES players(id),
+  means TEXT NOT NULL,
+  PRIMARY KEY (game_id, seq)
+);
+CREATE TABLE IF NOT EXISTS items (
+  game_id TEXT NOT NULL REFERENCES games(id),
+  seq INTEGER NOT NULL,
+  seconds INTEGER,
+  player_id INTEGER REFERENCES players(id),
+  item TEXT NOT NULL,
+  PRIMARY KEY (game_id, seq)
+);
+CREATE TABLE IF NOT EXISTS chat (
+  game_id TEXT NOT NULL REFERENCES games(id),
+  seq INTEGER NOT NULL,
+  seconds INTEGER,
+  player_id INTEGER REFERENCES players(id),
+  message TEXT NOT NULL,
+  PRIMARY KEY (game_id, seq)
+);
+CREATE TABLE IF NOT EXISTS awards (
+  game_id TEXT NOT NULL REFERENCES games(id),
+  seq INTEGER NOT NULL,
+  player_id INTEGER NOT NULL REFERENCES players(id),
+  name TEXT NOT NULL,
+  value REAL NOT NULL,
+  PRIMARY KEY (game_id, seq)
+);
+CREATE TABLE IF NOT EXISTS server_config (
+  game_id TEXT NOT NULL REFERENCES games(id),
+  key TEXT NOT NULL,
+  value TEXT NOT NULL,
+  PRIMARY KEY (game_id, key)
+);
+`
+
+// SQLWriter writes games as a SQL dump with a normalized schema. The dump
+// uses plain SQL accepted by SQLite, so it can be loaded with
+// `sqlite3 games.db < dump.sql`. Players are identified by their username,
+// the <world> kills have a NULL killer.
+type SQLWriter struct {
+	w            *bufio.Writer
+	knownPlayers map[string]bool
+	writtenGames map[string]bool
+	wroteHeader  bool
+}
+
+func NewSQLWriter(w io.Writer) *SQLWriter {
+	return &SQLWriter{
+		w:            bufio.NewWriter(w),
+		knownPlayers: make(map[string]bool),
+		writtenGames: make(map[string]bool),
+	}
+}
+
+// Write adds the rows of a game to the dump, games with a hash already
+// written are skipped.
+func (sw *SQLWriter) Write(game NamedGame) error {
+	if !sw.wroteHeader {
+		fmt.Fprintln(sw.w, "BEGIN TRANSACTION;")
+		fmt.Fprint(sw.w, schema)
+		sw.wroteHeader = true
+	}
+
+	g := game.Game
+	id := g.Hash()
+	if sw.writtenGames[id] {
+		return nil
+	}
+	sw.writtenGames[id] = true
+	gameType, err := strconv.Atoi(g.ServerConfig["g_gametype"])
+	gameTypeValue := "NULL"
+	if err == nil {
+		gameTypeValue = strconv.Itoa(gameType)
+	}
+	result := g.Result()
+	winner, winningTeam := "NULL", "NULL"
+	if result.Winner != "" {
+		winner = sw.player(result.Winner)
+	}
+	if result.WinningTeam != "" {
+		winningTeam = quote(result.WinningTeam)
+	}
+	fmt.Fprintf(sw.w, "INSERT OR IGNORE INTO games VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %d);\n",
+		quote(id), quote(game.Name), quote(g.ServerConfig["mapname"]), gameTypeValue,
+		seconds(g.StartTime), seconds(g.EndTime), quote(g.EndingReason), quote(result.Condition.String()),
+		winner, winningTeam, g.TotalKills)
+
+	keys := make([]string, 0, len(g.ServerConfig))
+	for k := range g.ServerConfig {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(sw.w, "INSERT OR IGNORE INTO server_config VALUES (%s, %s, %s);\n", quote(id), quote(k), quote(g.ServerConfig[k]))
+	}
+
+	ids := make([]int, 0, len(g.PlayersInfoById))
+	for clientId := range g.PlayersInfoById {
+		ids = append(ids, clientId)
+	}
+	sort.Ints(ids)
+	placeByName := make(map[string]int, len(result.Placements))
+	for _, p := range result.Placements {
+		placeByName[p.Name] = p.Place
+	}
+	seq := 0
+	for _, clientId := range ids {
+		seq++
+		pi := g.PlayersInfoById[clientId]
+		sw.writePlayerStats(id, seq, pi, strconv.Itoa(placeByName[pi.Username]), false)
+	}
+	for _, pi := range g.DisconnectedPlayers {
+		seq++
+		sw.writePlayerStats(id, seq, pi, "NULL", true)
+	}
+
+	for i, k := range g.Kills {
+		killer := "NULL"
+		if k.KillerId != 0 {
+			killer = sw.player(k.Killer)
+		}
+		fmt.Fprintf(sw.w, "INSERT OR IGNORE INTO kills VALUES (%s, %d, %s, %s, %s, %s);\n",
+			quote(id), i+1, seconds(k.Time), killer, sw.player(k.Victim), quote(k.Means))
+	}
+	for i, item := range g.Items {
+		player := "NULL"
+		if item.Username != "" {
+			player = sw.player(item.Username)
+		}
+		fmt.Fprintf(sw.w, "INSERT OR IGNORE INTO items VALUES (%s, %d, %s, %s, %s);\n",
+			quote(id), i+1, seconds(item.Time), player, quote(item.Item))
+	}
+	for i, c := range g.Chat {
+		player := "NULL"
+		if c.Username != "" {
+			player = sw.player(c.Username)
+		}
+		fmt.Fprintf(sw.w, "INSERT OR IGNORE INTO chat VALUES (%s, %d, %s, %s, %s);\n",
+			quote(id), i+1, seconds(c.Time), player, quote(c.Message))
+	}
+	for i, a := range g.Awards {
+		fmt.Fprintf(sw.w, "INSERT OR IGNORE INTO awards VALUES (%s, %d, %s, %s, %g);\n",
+			quote(id), i+1, sw.player(a.Player), quote(a.Name), a.Value)
+	}
+
+	return sw.w.Flush()
+}
+
+// Close ends the dump transaction.
+func (sw *SQLWriter) Close() error {
+	if !sw.wroteHeader {
+		fmt.Fprintln(sw.w, "BEGIN TRANSACTION;")
+		fmt.Fprint(sw.w, schema)
+	}
+	fmt.Fprintln(sw.w, "COMMIT;")
+	return sw.w.Flush()
+}
+
+func (sw *SQLWriter) writePlayerStats(gameId string, seq int, pi *parser.PlayersInfo, placement string, disconnected bool) {
+	d := 0
+	if disconnected {
+		d = 1
+	}
+	fmt.Fprintf(sw.w, "INSERT OR IGNORE INTO player_game_stats VALUES (%s, %d, %s, %d, %d, %s, %d, %d, %d, %d);\n",
+		quote(gameId), seq, sw.player(pi.Username), pi.Id, d, placement, pi.Score, pi.KillCount, pi.DeathCount, pi.SuicideCount)
+}
+
+// player returns the id of the player, writing its row on first use. The
+// subquery keeps the ids right when the dump is loaded on a database that
+// already has players.
+func (sw *SQLWriter) player(name string) string {
+	if !sw.knownPlayers[name] {
+		sw.knownPlayers[name] = true
+		fmt.Fprintf(sw.w, "INSERT OR IGNORE INTO players (name) VALUES (%s);\n", quote(name))
+	}
+	return fmt.Sprintf("(SELECT id FROM players WHERE name = %s)", quote(name))
+}
+
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func seconds(t string) string {
+	d, err := parser.ParseTime(t)
+	if err != nil {
+		return "NULL"
+	}
+	return strconv.Itoa(int(d.Seconds()))
+}
@@ -0,0 +1,58 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+func TestSQLWriter(t *testing.T) {
+	input := `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\O'Neil\t\0
+  0:02 Item: 2 weapon_railgun
+  0:03 say: O'Neil: it's me
+  0:05 Kill: 1022 2 22: <world> killed O'Neil by MOD_FALLING
+  1:10 ShutdownGame:
+`
+	gs := parser.InitScanner(bufio.NewScanner(strings.NewReader(input)))
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil {
+		t.Fatalf("could not parse game: %v", err)
+	}
+
+	var buf bytes.Buffer
+	writer := NewSQLWriter(&buf)
+	for i := 0; i < 2; i++ {
+		if err := writer.Write(NamedGame{Name: "game-1", Game: game}); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	dump := buf.String()
+	id := game.Hash()
+	player := "(SELECT id FROM players WHERE name = 'O''Neil')"
+	expected := []string{
+		"BEGIN TRANSACTION;\n",
+		"INSERT OR IGNORE INTO games VALUES ('" + id + "', 'game-1', 'q3dm17', 0, 0, 70, 'SERVER_UNEXPECTED_SHUTDOWN', 'shutdown', " + player + ", NULL, 1);\n",
+		"INSERT OR IGNORE INTO server_config VALUES ('" + id + "', 'mapname', 'q3dm17');\n",
+		"INSERT OR IGNORE INTO players (name) VALUES ('O''Neil');\n",
+		"INSERT OR IGNORE INTO player_game_stats VALUES ('" + id + "', 1, " + player + ", 2, 0, 1, -1, 0, 1, 0);\n",
+		"INSERT OR IGNORE INTO kills VALUES ('" + id + "', 1, 5, NULL, " + player + ", 'MOD_FALLING');\n",
+		"INSERT OR IGNORE INTO items VALUES ('" + id + "', 1, 2, " + player + ", 'weapon_railgun');\n",
+		"INSERT OR IGNORE INTO chat VALUES ('" + id + "', 1, 3, " + player + ", 'it''s me');\n",
+		"INSERT OR IGNORE INTO awards VALUES ('" + id + "', 1, " + player + ", 'Most Deaths to the Environment', 1);\n",
+		"COMMIT;\n",
+	}
+	for _, statement := range expected {
+		if strings.Count(dump, statement) != 1 {
+			t.Errorf("Expected dump to have once %q", statement)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+type field struct {
+	kind kind
+	// player fields need a player to be evaluated
+	player bool
+	get    func(e env) value
+}
+
+func number(get func(e env) int) *field {
+	return &field{kind: kNumber, get: func(e env) value { return value{n: float64(get(e))} }}
+}
+
+func text(get func(e env) string) *field {
+	return &field{kind: kString, get: func(e env) value { return value{s: get(e)} }}
+}
+
+func playerNumber(get func(info *parser.PlayersInfo) int) *field {
+	return &field{kind: kNumber, player: true, get: func(e env) value { return value{n: float64(get(e.player))} }}
+}
+
+func playerText(get func(info *parser.PlayersInfo) string) *field {
+	return &field{kind: kString, player: true, get: func(e env) value { return value{s: get(e.player)} }}
+}
+
+// fields are the fields of the game and of the players, config.<key> and
+// tag.<name> are resolved by lookupField.
+var fields = map[string]*field{
+	"map": text(func(e env) string { return e.game.ServerConfig["mapname"] }),
+	"gametype": number(func(e env) int {
+		gameType, _ := strconv.Atoi(e.game.ServerConfig["g_gametype"])
+		return gameType
+	}),
+	"players": number(func(e env) int { return len(players(e.game)) }),
+	"humans": number(func(e env) int {
+		count := 0
+		for _, info := range players(e.game) {
+			if !info.Bot {
+				count++
+			}
+		}
+		return count
+	}),
+	"duration": {kind: kDuration, get: func(e env) value {
+		d, _ := e.game.Duration()
+		return value{d: d}
+	}},
+	"kills":      number(func(e env) int { return e.game.TotalKills }),
+	"team_kills": number(func(e env) int { return e.game.TotalTeamKills }),
+	"ending":     text(func(e env) string { return e.game.EndingReason }),
+	"condition":  text(func(e env) string { return e.game.Result().Condition.String() }),
+	"winner":     text(func(e env) string { return e.game.Result().Winner }),
+	"dialect":    text(func(e env) string { return e.game.Dialect }),
+	"scoring":    text(func(e env) string { return e.game.ScoringRules }),
+	"shutdown":   {kind: kBool, get: func(e env) value { return value{b: e.game.Shutdown} }},
+
+	"player.name":         playerText(func(info *parser.PlayersInfo) string { return info.Username }),
+	"player.team":         playerText(func(info *parser.PlayersInfo) string { return info.Team.String() }),
+	"player.score":        playerNumber(func(info *parser.PlayersInfo) int { return info.Score }),
+	"player.kills":        playerNumber(func(info *parser.PlayersInfo) int { return info.KillCount }),
+	"player.deaths":       playerNumber(func(info *parser.PlayersInfo) int { return info.DeathCount }),
+	"player.suicides":     playerNumber(func(info *parser.PlayersInfo) int { return info.SuicideCount }),
+	"player.team_kills":   playerNumber(func(info *parser.PlayersInfo) int { return info.TeamKillCount }),
+	"player.best_streak":  playerNumber(func(info *parser.PlayersInfo) int { return info.BestKillStreak }),
+	"player.handicap":     playerNumber(func(info *parser.PlayersInfo) int { return info.Handicap }),
+	"player.captures":     playerNumber(func(info *parser.PlayersInfo) int { return info.CTF.Captures }),
+	"player.damage_given": playerNumber(func(info *parser.PlayersInfo) int { return info.DamageGiven }),
+	"player.bot":          {kind: kBool, player: true, get: func(e env) value { return value{b: e.player.Bot} }},
+}
+
+// FieldNames returns the names of the fields, without config.<key> and
+// tag.<name>.
+func FieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupField(name string) (*field, error) {
+	if f, ok := fields[name]; ok {
+		return f, nil
+	}
+	if key, ok := strings.CutPrefix(name, "config."); ok && key != "" {
+		return text(func(e env) string { return e.game.ServerConfig[key] }), nil
+	}
+	if tagName, ok := strings.CutPrefix(name, "tag."); ok {
+		tag, err := parser.ParseGameTags(tagName)
+		if err != nil || tag == 0 {
+			return nil, fmt.Errorf("unknown tag on where: %s", tagName)
+		}
+		return &field{kind: kBool, get: func(e env) value { return value{b: e.game.Tags.Any(tag)} }}, nil
+	}
+	return nil, fmt.Errorf("unknown field on where: %s", name)
+}
@@ -0,0 +1,397 @@
+// Package filter parses the expressions of the -where flag, which select the
+// games and the players shown on the output, e.g.
+//
+//	map == "q3dm17" && players >= 4 && duration > 5m
+//	player.kills > 10 || config.sv_hostname =~ "Code Miner"
+//
+// An expression compares fields of the game, of the server config and of the
+// players with literals or other fields, joined by &&, || and !. Strings are
+// quoted, numbers are plain and durations have a unit (30s, 5m, 1h30m). The
+// types are checked when the expression is parsed.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+// Filter is a parsed expression.
+type Filter struct {
+	source string
+	root   node
+	// usesPlayer is true when the expression has player fields, then it is
+	// evaluated for each player of the game.
+	usesPlayer bool
+}
+
+// Parse parses an expression of the filter language.
+func Parse(source string) (*Filter, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tkEOF {
+		return nil, fmt.Errorf("unexpected %s at %d on where", tok, tok.pos)
+	}
+	if root.kind() != kBool {
+		return nil, fmt.Errorf("expecting a condition on where, got a %s", root.kind())
+	}
+	return &Filter{source: source, root: root, usesPlayer: p.usesPlayer}, nil
+}
+
+func (f *Filter) String() string {
+	return f.source
+}
+
+// MatchGame tells if the game is selected. Expressions with player fields
+// select the games where at least one player matches, games without players
+// are evaluated with the comparisons of player fields false.
+func (f *Filter) MatchGame(game *parser.Game) bool {
+	infos := players(game)
+	if !f.usesPlayer || len(infos) == 0 {
+		return f.root.eval(env{game: game}).b
+	}
+	for _, info := range infos {
+		if f.root.eval(env{game: game, player: info}).b {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPlayer tells if a player of the game is shown. Every player is shown
+// when the expression has no player fields.
+func (f *Filter) MatchPlayer(game *parser.Game, info *parser.PlayersInfo) bool {
+	if !f.usesPlayer {
+		return true
+	}
+	return f.root.eval(env{game: game, player: info}).b
+}
+
+func players(game *parser.Game) []*parser.PlayersInfo {
+	var infos []*parser.PlayersInfo
+	add := func(info *parser.PlayersInfo) {
+		if info.Id != 0 && info.Id != parser.UnknownPlayerId && info.Username != "" {
+			infos = append(infos, info)
+		}
+	}
+	for _, info := range game.PlayersInfoById {
+		add(info)
+	}
+	for _, info := range game.DisconnectedPlayers {
+		add(info)
+	}
+	return infos
+}
+
+type kind int
+
+const (
+	kBool kind = iota
+	kNumber
+	kString
+	kDuration
+)
+
+func (k kind) String() string {
+	switch k {
+	case kBool:
+		return "bool"
+	case kNumber:
+		return "number"
+	case kString:
+		return "string"
+	case kDuration:
+		return "duration"
+	}
+	return "unknown"
+}
+
+// value is the result of a node, missing when it is a player field evaluated
+// without a player.
+type value struct {
+	b       bool
+	n       float64
+	s       string
+	d       time.Duration
+	missing bool
+}
+
+type env struct {
+	game   *parser.Game
+	player *parser.PlayersInfo
+}
+
+type node interface {
+	kind() kind
+	eval(e env) value
+}
+
+type literal struct {
+	k kind
+	v value
+}
+
+func (l *literal) kind() kind       { return l.k }
+func (l *literal) eval(e env) value { return l.v }
+
+type fieldNode struct {
+	f *field
+}
+
+func (n *fieldNode) kind() kind { return n.f.kind }
+func (n *fieldNode) eval(e env) value {
+	if n.f.player && e.player == nil {
+		return value{missing: true}
+	}
+	return n.f.get(e)
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) kind() kind       { return kBool }
+func (n *notNode) eval(e env) value { return value{b: !n.operand.eval(e).b} }
+
+type logicNode struct {
+	and         bool
+	left, right node
+}
+
+func (n *logicNode) kind() kind { return kBool }
+func (n *logicNode) eval(e env) value {
+	left := n.left.eval(e).b
+	if n.and {
+		return value{b: left && n.right.eval(e).b}
+	}
+	return value{b: left || n.right.eval(e).b}
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) kind() kind { return kBool }
+func (n *compareNode) eval(e env) value {
+	l, r := n.left.eval(e), n.right.eval(e)
+	if l.missing || r.missing {
+		return value{}
+	}
+	var c int
+	switch n.left.kind() {
+	case kBool:
+		if l.b != r.b {
+			c = 1
+		}
+	case kNumber:
+		c = compare(l.n, r.n)
+	case kString:
+		c = strings.Compare(l.s, r.s)
+	case kDuration:
+		c = compare(l.d, r.d)
+	}
+	switch n.op {
+	case "==":
+		return value{b: c == 0}
+	case "!=":
+		return value{b: c != 0}
+	case "<":
+		return value{b: c < 0}
+	case "<=":
+		return value{b: c <= 0}
+	case ">":
+		return value{b: c > 0}
+	case ">=":
+		return value{b: c >= 0}
+	}
+	return value{}
+}
+
+func compare[T float64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+type matchNode struct {
+	left node
+	re   *regexp.Regexp
+}
+
+func (n *matchNode) kind() kind { return kBool }
+func (n *matchNode) eval(e env) value {
+	left := n.left.eval(e)
+	return value{b: !left.missing && n.re.MatchString(left.s)}
+}
+
+type exprParser struct {
+	tokens     []token
+	pos        int
+	usesPlayer bool
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tkEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tkOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logic(false, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tkAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logic(true, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func logic(and bool, left, right node) (node, error) {
+	if left.kind() != kBool || right.kind() != kBool {
+		return nil, fmt.Errorf("expecting conditions around && and || on where, got %s and %s", left.kind(), right.kind())
+	}
+	return &logicNode{and: and, left: left, right: right}, nil
+}
+
+func (p *exprParser) parseUnary() (node, error) {
+	if p.peek().kind != tkNot {
+		return p.parseComparison()
+	}
+	p.next()
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if operand.kind() != kBool {
+		return nil, fmt.Errorf("expecting a condition after ! on where, got a %s", operand.kind())
+	}
+	return &notNode{operand}, nil
+}
+
+func (p *exprParser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.peek()
+	switch op.kind {
+	case tkCompare:
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if left.kind() != right.kind() {
+			return nil, fmt.Errorf("can not compare %s with %s at %d on where", left.kind(), right.kind(), op.pos)
+		}
+		if left.kind() == kBool && op.text != "==" && op.text != "!=" {
+			return nil, fmt.Errorf("can not use %s on bools at %d on where", op.text, op.pos)
+		}
+		return &compareNode{op: op.text, left: left, right: right}, nil
+	case tkMatch:
+		p.next()
+		pattern := p.next()
+		if left.kind() != kString || pattern.kind != tkString {
+			return nil, fmt.Errorf("expecting a string field and a quoted pattern around =~ at %d on where", op.pos)
+		}
+		re, err := regexp.Compile(pattern.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern on where: %w", err)
+		}
+		return &matchNode{left: left, re: re}, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseOperand() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tkLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tkRParen {
+			return nil, fmt.Errorf("expecting ) at %d on where", closing.pos)
+		}
+		return inner, nil
+	case tkString:
+		return &literal{k: kString, v: value{s: tok.text}}, nil
+	case tkNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s at %d on where", tok.text, tok.pos)
+		}
+		return &literal{k: kNumber, v: value{n: n}}, nil
+	case tkDuration:
+		d, err := time.ParseDuration(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %s at %d on where", tok.text, tok.pos)
+		}
+		return &literal{k: kDuration, v: value{d: d}}, nil
+	case tkIdent:
+		switch tok.text {
+		case "true":
+			return &literal{k: kBool, v: value{b: true}}, nil
+		case "false":
+			return &literal{k: kBool, v: value{b: false}}, nil
+		}
+		f, err := lookupField(tok.text)
+		if err != nil {
+			return nil, err
+		}
+		if f.player {
+			p.usesPlayer = true
+		}
+		return &fieldNode{f}, nil
+	}
+	return nil, fmt.Errorf("unexpected %s at %d on where", tok, tok.pos)
+}
@@ -0,0 +1,154 @@
+package filter
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+const input = `  0:00 InitGame: \sv_hostname\Code Miner Server\g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Sarge\t\0\skill\ 2.00
+  1:00 Kill: 2 3 10: Isgalamido killed Zeh by MOD_RAILGUN
+  2:00 Kill: 2 3 10: Isgalamido killed Zeh by MOD_RAILGUN
+  6:00 Kill: 1022 2 22: <world> killed Isgalamido by MOD_TRIGGER_HURT
+  6:10 Exit: Fraglimit hit.
+  6:10 ShutdownGame:
+`
+
+func parseGame(t *testing.T) *parser.Game {
+	gs := parser.InitScanner(bufio.NewScanner(strings.NewReader(input)))
+	game, _, err := gs.GetGame()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return game
+}
+
+func TestMatchGame(t *testing.T) {
+	game := parseGame(t)
+	tests := map[string]struct {
+		expression string
+		expected   bool
+	}{
+		"Map":           {expression: `map == "q3dm17"`, expected: true},
+		"OtherMap":      {expression: `map == "q3dm6"`, expected: false},
+		"Combined":      {expression: `map == "q3dm17" && players >= 3 && duration > 5m`, expected: true},
+		"Humans":        {expression: `humans == 2 && gametype == 0`, expected: true},
+		"Duration":      {expression: `duration >= 6m10s`, expected: true},
+		"Or":            {expression: `kills > 10 || winner == "Isgalamido"`, expected: true},
+		"Not":           {expression: `!(kills == 3)`, expected: false},
+		"Config":        {expression: `config.sv_hostname =~ "^Code Miner"`, expected: true},
+		"MissingConfig": {expression: `config.g_redteam == ""`, expected: true},
+		"Tag":           {expression: `tag.short || !shutdown`, expected: false},
+		"Condition":     {expression: `condition == "fraglimit"`, expected: true},
+		"Player":        {expression: `player.kills > 1`, expected: true},
+		"NoPlayer":      {expression: `player.kills > 2`, expected: false},
+		"PlayerBot":     {expression: `player.bot && player.name == "Sarge"`, expected: true},
+		"Negative":      {expression: `player.score < -0`, expected: false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := Parse(test.expression)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result := f.MatchGame(game); result != test.expected {
+				t.Errorf("Expected %v, but got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestMatchGameWithoutPlayers(t *testing.T) {
+	gs := parser.InitScanner(bufio.NewScanner(strings.NewReader("  0:00 InitGame: \\mapname\\q3dm17\n  0:10 ShutdownGame:\n")))
+	game, _, err := gs.GetGame()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tests := map[string]struct {
+		expression string
+		expected   bool
+	}{
+		"Or":       {expression: `player.kills > 10 || map == "q3dm17"`, expected: true},
+		"And":      {expression: `player.kills < 10 && map == "q3dm17"`, expected: false},
+		"Match":    {expression: `player.name =~ "" || map == "q3dm6"`, expected: false},
+		"GameOnly": {expression: `map == "q3dm17"`, expected: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := Parse(test.expression)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result := f.MatchGame(game); result != test.expected {
+				t.Errorf("Expected %v, but got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestMatchPlayer(t *testing.T) {
+	game := parseGame(t)
+	tests := map[string]struct {
+		expression string
+		expected   []string
+	}{
+		"PlayerField": {expression: `player.kills > 1 || player.deaths > 1`, expected: []string{"Isgalamido", "Zeh"}},
+		"GameField":   {expression: `map == "q3dm17"`, expected: []string{"Isgalamido", "Sarge", "Zeh"}},
+		"Mixed":       {expression: `map == "q3dm6" || !player.bot`, expected: []string{"Isgalamido", "Zeh"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := Parse(test.expression)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var names []string
+			for _, name := range []string{"Isgalamido", "Sarge", "Zeh"} {
+				for _, info := range game.PlayersInfoById {
+					if info.Username == name && f.MatchPlayer(game, info) {
+						names = append(names, name)
+					}
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(test.expected, ",") {
+				t.Errorf("Expected %v, but got %v", test.expected, names)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"UnknownField":  `frags > 1`,
+		"TypeMismatch":  `duration > 5`,
+		"NotCondition":  `kills`,
+		"BoolOrder":     `shutdown < true`,
+		"Unterminated":  `map == "q3dm17`,
+		"MissingParen":  `(kills > 1`,
+		"Trailing":      `kills > 1 kills`,
+		"BadPattern":    `map =~ "("`,
+		"PatternField":  `kills =~ "1"`,
+		"UnknownTag":    `tag.boring`,
+		"BadCharacter":  `kills > 1 & map == ""`,
+		"LogicOnNumber": `kills && shutdown`,
+	}
+
+	for name, expression := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(expression); err == nil {
+				t.Errorf("Expected error, but got nil")
+			}
+		})
+	}
+}
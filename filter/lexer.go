@@ -0,0 +1,129 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tkEOF tokenKind = iota
+	tkIdent
+	tkString
+	tkNumber
+	tkDuration
+	tkCompare
+	tkMatch
+	tkAnd
+	tkOr
+	tkNot
+	tkLParen
+	tkRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is the offset of the token on the expression.
+	pos int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tkEOF:
+		return "end of expression"
+	case tkString:
+		return fmt.Sprintf("%q", t.text)
+	}
+	return t.text
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// lex splits the expression in tokens, ending with a tkEOF token.
+func lex(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tkLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tkRParen, ")", i})
+			i++
+		case r == '"':
+			var sb strings.Builder
+			start := i
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated string at %d on where", start)
+			}
+			i++
+			tokens = append(tokens, token{tkString, sb.String(), start})
+		case unicode.IsDigit(r) || r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			kind := tkNumber
+			// a unit makes it a duration, e.g. 5m or 1h30m
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				kind = tkDuration
+				i++
+			}
+			tokens = append(tokens, token{kind, string(runes[start:i]), start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && isIdentRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tkIdent, string(runes[start:i]), start})
+		default:
+			start := i
+			op := string(r)
+			if i+1 < len(runes) {
+				op = string(runes[i : i+2])
+			}
+			switch op {
+			case "&&":
+				tokens = append(tokens, token{tkAnd, op, start})
+				i += 2
+			case "||":
+				tokens = append(tokens, token{tkOr, op, start})
+				i += 2
+			case "==", "!=", "<=", ">=":
+				tokens = append(tokens, token{tkCompare, op, start})
+				i += 2
+			case "=~":
+				tokens = append(tokens, token{tkMatch, op, start})
+				i += 2
+			default:
+				switch r {
+				case '<', '>':
+					tokens = append(tokens, token{tkCompare, string(r), start})
+				case '!':
+					tokens = append(tokens, token{tkNot, "!", start})
+				default:
+					return nil, fmt.Errorf("unexpected character %q at %d on where", r, start)
+				}
+				i++
+			}
+		}
+	}
+	return append(tokens, token{tkEOF, "", len(runes)}), nil
+}
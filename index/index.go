@@ -0,0 +1,167 @@
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+// SegmentEnding tells how the game of a segment ended on the log.
+type SegmentEnding uint8
+
+const (
+	// SEShutdown segments end with a ShutdownGame line.
+	SEShutdown SegmentEnding = iota
+	// SENextInit segments end at the InitGame of the next game.
+	SENextInit
+	// SEEOF segments end with the file, the game may still be running.
+	SEEOF
+)
+
+// Segment is the byte range of a game on the log, from the beginning of its
+// InitGame line to the end of its last line.
+type Segment struct {
+	Start  int64
+	End    int64
+	Ending SegmentEnding
+}
+
+// Index lists the games of a log file. Size and ModTime identify the version
+// of the file that was indexed.
+type Index struct {
+	Path     string
+	Size     int64
+	ModTime  time.Time
+	Segments []Segment
+}
+
+// Build reads the whole log recording the offsets of the InitGame and
+// ShutdownGame lines.
+func Build(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &Index{
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	var open *Segment
+	for {
+		line, err := readLine(reader)
+		if len(line) > 0 {
+			words := bytes.Fields(line)
+			if len(words) > 1 {
+				switch parser.GetLogHeader(string(words[1])) {
+				case parser.LHInitGame:
+					if open != nil {
+						open.End = offset
+						open.Ending = SENextInit
+						idx.Segments = append(idx.Segments, *open)
+					}
+					open = &Segment{Start: offset}
+				case parser.LHShutdownGame:
+					if open != nil {
+						open.End = offset + int64(len(line))
+						open.Ending = SEShutdown
+						idx.Segments = append(idx.Segments, *open)
+						open = nil
+					}
+				}
+			}
+			offset += int64(len(line))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if open != nil {
+		open.End = offset
+		open.Ending = SEEOF
+		idx.Segments = append(idx.Segments, *open)
+	}
+
+	return idx, nil
+}
+
+// readLine returns the next line including its line break, whatever its size.
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadSlice('\n')
+	if !errors.Is(err, bufio.ErrBufferFull) {
+		return line, err
+	}
+	full := append([]byte(nil), line...)
+	for errors.Is(err, bufio.ErrBufferFull) {
+		line, err = reader.ReadSlice('\n')
+		full = append(full, line...)
+	}
+	return full, err
+}
+
+// Matches tells if the index was built from the current version of the file.
+func (idx *Index) Matches(info os.FileInfo) bool {
+	return idx.Size == info.Size() && idx.ModTime.Equal(info.ModTime())
+}
+
+func (idx *Index) Save(path string) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	idx := &Index{}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// LoadOrBuild reuses the index saved on indexPath when it matches the log,
+// otherwise the log is indexed again and the index saved.
+func LoadOrBuild(logPath string, indexPath string) (*Index, error) {
+	info, err := os.Stat(logPath)
+	if err != nil {
+		return nil, err
+	}
+	if idx, err := Load(indexPath); err == nil && idx.Matches(info) {
+		return idx, nil
+	}
+
+	idx, err := Build(logPath)
+	if err != nil {
+		return nil, err
+	}
+	return idx, idx.Save(indexPath)
+}
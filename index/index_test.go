@@ -0,0 +1,77 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testLog = `  0:00 InitGame: \mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:02 ShutdownGame:
+  0:02 ------------------------------------------------------------
+  0:00 InitGame: \mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:00 InitGame: \mapname\q3dm17
+  0:01 Exit: Fraglimit hit.
+`
+
+func writeLog(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "games.log")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write log: %s", err)
+	}
+	return path
+}
+
+func TestBuild(t *testing.T) {
+	idx, err := Build(writeLog(t, testLog))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []Segment{
+		{Start: 0, End: 78, Ending: SEShutdown},
+		{Start: 146, End: 203, Ending: SENextInit},
+		{Start: 203, End: 264, Ending: SEEOF},
+	}
+	if len(idx.Segments) != len(expected) {
+		t.Fatalf("Expected %d segments, got %+v", len(expected), idx.Segments)
+	}
+	for i, seg := range expected {
+		if idx.Segments[i] != seg {
+			t.Errorf("Expected segment %d to be %+v, got %+v", i, seg, idx.Segments[i])
+		}
+	}
+}
+
+func TestLoadOrBuild(t *testing.T) {
+	logPath := writeLog(t, testLog)
+	indexPath := logPath + ".idx"
+
+	idx, err := LoadOrBuild(logPath, indexPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	saved, err := Load(indexPath)
+	if err != nil {
+		t.Fatalf("Expected index to be saved: %s", err)
+	}
+	if len(saved.Segments) != len(idx.Segments) || !saved.ModTime.Equal(idx.ModTime) {
+		t.Errorf("Expected saved index %+v, got %+v", idx, saved)
+	}
+
+	// a changed log must be indexed again
+	if err := os.WriteFile(logPath, []byte(testLog+"  0:02 ShutdownGame:\n"), 0o644); err != nil {
+		t.Fatalf("could not write log: %s", err)
+	}
+	idx, err = LoadOrBuild(logPath, indexPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if last := idx.Segments[len(idx.Segments)-1]; last.Ending != SEShutdown {
+		t.Errorf("Expected index to be rebuilt, got %+v", idx.Segments)
+	}
+}
@@ -1,55 +1,353 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"time"
 
+	"github.com/pedroegsilva/cw-test/batch"
+	"github.com/pedroegsilva/cw-test/filter"
+	"github.com/pedroegsilva/cw-test/index"
 	"github.com/pedroegsilva/cw-test/parser"
 	"github.com/pedroegsilva/cw-test/reports"
+	"github.com/pedroegsilva/cw-test/store"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
+// pathList is a flag that can be repeated to receive many files.
+type pathList []string
+
+func (p *pathList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pathList) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+// tagFlags registers the flags to tag and exclude games on fs. The returned
+// function reads them after fs is parsed.
+func tagFlags(fs *flag.FlagSet) func() (parser.TagOptions, parser.GameTag, error) {
+	defaults := parser.DefaultTagOptions()
+	exclude := fs.String("exclude", "", "leave games with these tags out of the reports and aggregates, comma separated: warmup, few-humans, no-kills, bot-only and short")
+	minHumans := fs.Int("min-humans", defaults.MinHumans, "games with less human players are tagged few-humans")
+	minDuration := fs.Duration("min-duration", defaults.MinDuration, "shorter games are tagged short")
+	return func() (parser.TagOptions, parser.GameTag, error) {
+		tags, err := parser.ParseGameTags(*exclude)
+		return parser.TagOptions{MinHumans: *minHumans, MinDuration: *minDuration}, tags, err
+	}
+}
+
+// scoringFlag registers the -scoring flag on fs. The returned function loads
+// the rules after fs is parsed.
+func scoringFlag(fs *flag.FlagSet) func() (parser.ScoringRules, error) {
+	path := fs.String("scoring", "", "json file with the scoring rules, defaults to the Quake 3 scoring")
+	return func() (parser.ScoringRules, error) {
+		if *path == "" {
+			return parser.DefaultScoringRules(), nil
+		}
+		rules, err := parser.LoadScoringRules(*path)
+		if err != nil {
+			return rules, fmt.Errorf("could not load scoring rules: %w", err)
+		}
+		return rules, nil
+	}
+}
+
+// awardsFlag registers the -awards flag on fs. The returned function loads
+// the rules after fs is parsed, nil rules are the default awards.
+func awardsFlag(fs *flag.FlagSet) func() ([]parser.AwardRule, error) {
+	path := fs.String("awards", "", "json file with the award rules, defaults to the built-in awards")
+	return func() ([]parser.AwardRule, error) {
+		if *path == "" {
+			return nil, nil
+		}
+		rules, err := parser.LoadAwardRules(*path)
+		if err != nil {
+			return nil, fmt.Errorf("could not load award rules: %w", err)
+		}
+		return rules, nil
+	}
+}
+
+// whereFlag registers the -where flag on fs. The returned function parses the
+// expression after fs is parsed, nil when it is empty.
+func whereFlag(fs *flag.FlagSet) func() (*filter.Filter, error) {
+	expression := fs.String("where", "", `only the games and players matching the expression, e.g. 'map == "q3dm17" && player.kills > 10'`)
+	return func() (*filter.Filter, error) {
+		if *expression == "" {
+			return nil, nil
+		}
+		return filter.Parse(*expression)
+	}
+}
+
+// dialectFlag registers the -dialect flag on fs. The returned function reads
+// it after fs is parsed, a nil dialect is detected from each InitGame.
+func dialectFlag(fs *flag.FlagSet) func() (*parser.Dialect, error) {
+	name := fs.String("dialect", "auto", "log dialect: auto, baseq3, missionpack, openarena, cpma, osp or urt")
+	return func() (*parser.Dialect, error) {
+		if *name == "auto" || *name == "" {
+			return nil, nil
+		}
+		return parser.LookupDialect(*name)
+	}
+}
+
 func main() {
 	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
-	inputPath := flag.String("i", "", "full path of the log file")
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		case "query":
+			os.Exit(runQuery(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "rating":
+			os.Exit(runRating(os.Args[2:]))
+		case "weapons":
+			os.Exit(runWeapons(os.Args[2:]))
+		case "suspicion":
+			os.Exit(runSuspicion(os.Args[2:]))
+		case "diff":
+			os.Exit(runDiff(os.Args[2:]))
+		}
+	}
+
+	var inputPaths pathList
+	flag.Var(&inputPaths, "i", "full path of the log file, can be repeated. Extra arguments are also read as log files")
+	mode := flag.String("mode", "lenient", "error policy: strict, lenient or recover")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of log files, or of game segments with -split, parsed concurrently")
+	printS := flag.Bool("summary", false, "print the aggregated statistics of every game")
+	split := flag.Bool("split", false, "index each log by game and parse its games concurrently")
+	indexPath := flag.String("index", "", "index file used with -split, defaults to the log path with the .idx suffix. Only valid for a single log")
+	gameNumber := flag.Int("game", 0, "with -split, only parse the game on this position of the log")
+	checkpointDir := flag.String("checkpoint", "", "directory where the progress of each log is saved, a new run resumes from it")
+	storeDir := flag.String("store", "", "directory of the stats store where the games are ingested")
+	playedAtFlag := flag.String("played-at", "", "date the games were played (YYYY-MM-DD or RFC3339) saved on the store, defaults to the log modification time")
+	readTagFlags := tagFlags(flag.CommandLine)
+	readScoring := scoringFlag(flag.CommandLine)
+	readAwards := awardsFlag(flag.CommandLine)
+	readWhere := whereFlag(flag.CommandLine)
+	readDialect := dialectFlag(flag.CommandLine)
 	flag.Parse()
+	inputPaths = append(inputPaths, flag.Args()...)
 
-	if *inputPath == "" {
+	if len(inputPaths) == 0 {
 		flag.Usage()
 		return
 	}
 
+	policy, err := parser.ParseErrorPolicy(*mode)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		flag.Usage()
+		os.Exit(2)
+	}
+	tagOptions, exclude, err := readTagFlags()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		os.Exit(2)
+	}
+	scoring, err := readScoring()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		os.Exit(2)
+	}
+	awardRules, err := readAwards()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		os.Exit(2)
+	}
+	where, err := readWhere()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		os.Exit(2)
+	}
+	dialect, err := readDialect()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		os.Exit(2)
+	}
+
 	printJ := os.Getenv("OUT_JSON")
 	printH := os.Getenv("OUT_HUMAN")
 
-	file, err := os.Open(*inputPath)
-	if err != nil {
-		log.Error().Msg(fmt.Sprintf("Error opening file: %s", err))
-		return
+	if *split && *checkpointDir != "" {
+		log.Error().Msg("-checkpoint can not be used with -split")
+		os.Exit(2)
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	gameScanner := parser.InitScanner(scanner)
 
-	idx := 0
-	for game, ok, err := gameScanner.GetGame(); ok; game, ok, err = gameScanner.GetGame() {
-		idx++
+	var statsStore *store.Store
+	if *storeDir != "" {
+		statsStore, err = store.Open(*storeDir)
 		if err != nil {
-			log.Error().Msg(fmt.Sprintf("error on game-%d: %s", idx, err))
-			continue
+			log.Error().Msg(fmt.Sprintf("could not open store: %s", err))
+			os.Exit(2)
 		}
+	}
+	var playedAt time.Time
+	if *playedAtFlag != "" {
+		playedAt, err = parseDate(*playedAtFlag)
+		if err != nil {
+			log.Error().Msg(err.Error())
+			os.Exit(2)
+		}
+	}
 
+	summary := reports.NewSummary()
+	if awardRules != nil {
+		summary.AwardRules = awardRules
+	}
+	failed := false
+	// an interrupt stops the scans, the games read until then are reported
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	opts := batch.Options{
+		Context:       ctx,
+		Workers:       *workers,
+		ErrorPolicy:   policy,
+		CheckpointDir: *checkpointDir,
+		TagOptions:    tagOptions,
+		Scoring:       scoring,
+		Exclude:       exclude,
+		Dialect:       dialect,
+		Awards:        awardRules,
+		Where:         where,
+	}
+	printGame := func(gr batch.GameResult, name string, path string) {
+		if gr.Err != nil {
+			log.Error().Msg(fmt.Sprintf("error on %s: %s", name, gr.Err))
+			return
+		}
+		// the store keeps every game with its tags, -exclude and -where only
+		// select what is printed
+		if statsStore != nil {
+			meta := store.Meta{PlayedAt: playedAt, Source: path}
+			if meta.PlayedAt.IsZero() {
+				meta.PlayedAt = modTime(path)
+			}
+			if _, _, err := statsStore.Ingest(gr.Game, meta); err != nil {
+				log.Error().Msg(fmt.Sprintf("could not store %s: %s", name, err))
+				failed = true
+			}
+		}
+
+		if gr.Game.Tags.Any(exclude) || (where != nil && !where.MatchGame(gr.Game)) {
+			return
+		}
+		keep := batch.KeepPlayers(where, gr.Game)
 		if printJ != "" {
-			reports.PrintJson(game, fmt.Sprintf("game-%d", idx))
+			reports.PrintJson(gr.Game, name, keep)
+		}
+
+		if printH != "" {
+			reports.PrintHumanReadableReport(gr.Game, name, keep)
+		}
+	}
+
+	if *split {
+		if *indexPath != "" && len(inputPaths) > 1 {
+			log.Error().Msg("-index can only be used with a single log")
+			os.Exit(2)
+		}
+		var numbers []int
+		if *gameNumber > 0 {
+			numbers = []int{*gameNumber}
+		}
+		for _, path := range inputPaths {
+			idxPath := *indexPath
+			if idxPath == "" {
+				idxPath = path + ".idx"
+			}
+			idx, err := index.LoadOrBuild(path, idxPath)
+			if err != nil {
+				log.Error().Msg(fmt.Sprintf("could not index %s: %s", path, err))
+				failed = true
+				continue
+			}
+			err = batch.ProcessSegments(idx, numbers, opts, func(result *batch.SegmentResult) {
+				for _, gr := range result.Games {
+					printGame(gr, gameName(path, result.Number, len(inputPaths) > 1), path)
+				}
+				if result.Err != nil {
+					log.Error().Msg(fmt.Sprintf("aborting %s: %s", gameName(path, result.Number, len(inputPaths) > 1), result.Err))
+					failed = true
+				}
+				summary.Merge(result.Summary)
+			})
+			if err != nil {
+				log.Error().Msg(fmt.Sprintf("could not read %s: %s", path, err))
+				failed = true
+			}
 		}
+	} else {
+		batch.ProcessFiles(inputPaths, opts, func(result *batch.FileResult) {
+			for idx, gr := range result.Games {
+				printGame(gr, gameName(result.Path, result.FirstGame+idx+1, len(inputPaths) > 1), result.Path)
+			}
 
+			if result.Err != nil {
+				log.Error().Msg(fmt.Sprintf("aborting %s after game-%d: %s", result.Path, result.FirstGame+len(result.Games), result.Err))
+				failed = true
+			}
+			if err := result.SaveCheckpoint(); err != nil {
+				log.Error().Msg(fmt.Sprintf("could not save checkpoint of %s: %s", result.Path, err))
+				failed = true
+			}
+			summary.Merge(result.Summary)
+		})
+	}
+
+	if *printS {
+		if printJ != "" {
+			reports.PrintSummaryJson(summary)
+		}
 		if printH != "" {
-			reports.PrintHumanReadableReport(game, fmt.Sprintf("game-%d", idx))
+			reports.PrintHumanReadableSummary(summary)
 		}
 	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// gameName identifies a game by its position on the log. The file path is
+// only added when many files are processed.
+func gameName(path string, idx int, withPath bool) string {
+	if withPath {
+		return fmt.Sprintf("%s:game-%d", path, idx)
+	}
+	return fmt.Sprintf("game-%d", idx)
+}
+
+// parseDate accepts a date (YYYY-MM-DD) or a RFC3339 timestamp.
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date, expecting YYYY-MM-DD or RFC3339: %s", value)
+	}
+	return t, nil
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Now()
+	}
+	return info.ModTime()
 }
@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WeaponAccuracy are the shots and hits of a player with a weapon family,
+// read from the Weapon_Stats lines of OSP and CPMA or the Hit lines of Urban
+// Terror. Each dialect logs only part of them.
+type WeaponAccuracy struct {
+	Shots  int
+	Hits   int
+	Kills  int
+	Deaths int
+	// HitsReceived are the hits taken from the weapon, only on Hit lines.
+	HitsReceived int
+	// Headshots are the hits on the head or helmet, only on Hit lines.
+	Headshots int
+}
+
+// Accuracy is the share of the shots that hit, zero when the shots are not
+// logged.
+func (a WeaponAccuracy) Accuracy() float64 {
+	if a.Shots == 0 {
+		return 0
+	}
+	return float64(a.Hits) / float64(a.Shots)
+}
+
+// ospWeaponFamilies maps the weapon names of Weapon_Stats lines to the
+// families of the means of death.
+var ospWeaponFamilies = map[string]string{
+	"gauntlet":     "gauntlet",
+	"machinegun":   "machinegun",
+	"mg":           "machinegun",
+	"shotgun":      "shotgun",
+	"sg":           "shotgun",
+	"glauncher":    "grenadelauncher",
+	"grenade":      "grenadelauncher",
+	"gl":           "grenadelauncher",
+	"rlauncher":    "rocketlauncher",
+	"rocket":       "rocketlauncher",
+	"rl":           "rocketlauncher",
+	"lightning":    "lightning",
+	"lightninggun": "lightning",
+	"lg":           "lightning",
+	"railgun":      "railgun",
+	"rg":           "railgun",
+	"plasma":       "plasmagun",
+	"plasmagun":    "plasmagun",
+	"pg":           "plasmagun",
+	"bfg":          "bfg",
+	"bfg10k":       "bfg",
+	"grapple":      "grapplinghook",
+}
+
+func ospWeaponFamily(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, ".", ""))
+	if family, ok := ospWeaponFamilies[name]; ok {
+		return family
+	}
+	return name
+}
+
+// parseHit reads a Hit line of Urban Terror:
+//
+//	Hit: <victim> <attacker> <location> <weapon>: <attacker> hit <victim> in the <location>
+func (d *Dialect) parseHit(words []string) (Hit, error) {
+	if len(words) < 6 {
+		return Hit{}, &SyntaxError{LHHit, "expecting more than 6 words on log line"}
+	}
+	victimId, err := strconv.Atoi(words[2])
+	if err != nil {
+		return Hit{}, &SyntaxError{LHHit, "expecting victimId to be an integer"}
+	}
+	attackerId, err := strconv.Atoi(words[3])
+	if err != nil {
+		return Hit{}, &SyntaxError{LHHit, "expecting attackerId to be an integer"}
+	}
+	locationId, err := strconv.Atoi(words[4])
+	if err != nil {
+		return Hit{}, &SyntaxError{LHHit, "expecting location to be an integer"}
+	}
+	weaponId, err := strconv.Atoi(strings.TrimSuffix(words[5], ":"))
+	if err != nil {
+		return Hit{}, &SyntaxError{LHHit, "expecting weapon to be an integer"}
+	}
+
+	hit := Hit{
+		VictimId:   victimId,
+		AttackerId: attackerId,
+		LocationId: locationId,
+		Location:   strconv.Itoa(locationId),
+		WeaponId:   weaponId,
+		Weapon:     fmt.Sprintf("weapon_%d", weaponId),
+	}
+	if family, ok := d.HitWeapons[weaponId]; ok {
+		hit.Weapon = family
+	}
+	text := strings.Join(words[6:], " ")
+	if i := strings.LastIndex(text, " in the "); i >= 0 {
+		hit.Location = text[i+len(" in the "):]
+	}
+	return hit, nil
+}
+
+// parseWeaponStats reads a Weapon_Stats line of OSP and CPMA:
+//
+//	Weapon_Stats: <client> <weapon>:<hits>:<shots>:<kills>:<deaths> ... Given:<damage> Recvd:<damage>
+func parseWeaponStats(words []string) (WeaponStats, error) {
+	if len(words) < 3 {
+		return WeaponStats{}, &SyntaxError{LHWeaponStats, "expecting 3 or more words on log line"}
+	}
+	clientId, err := strconv.Atoi(words[2])
+	if err != nil {
+		return WeaponStats{}, &SyntaxError{LHWeaponStats, "expecting clientId to be an integer"}
+	}
+
+	stats := WeaponStats{ClientId: clientId, Weapons: make(map[string]WeaponAccuracy)}
+	for _, word := range words[3:] {
+		fields := strings.Split(word, ":")
+		values := make([]int, len(fields)-1)
+		for i, field := range fields[1:] {
+			if values[i], err = strconv.Atoi(field); err != nil {
+				return WeaponStats{}, &SyntaxError{LHWeaponStats, fmt.Sprintf("expecting integers on %s", word)}
+			}
+		}
+		switch len(values) {
+		case 1:
+			switch fields[0] {
+			case "Given":
+				stats.DamageGiven = values[0]
+			case "Recvd":
+				stats.DamageReceived = values[0]
+			}
+		case 4:
+			family := ospWeaponFamily(fields[0])
+			a := stats.Weapons[family]
+			a.Hits += values[0]
+			a.Shots += values[1]
+			a.Kills += values[2]
+			a.Deaths += values[3]
+			stats.Weapons[family] = a
+		default:
+			return WeaponStats{}, &SyntaxError{LHWeaponStats, fmt.Sprintf("expecting weapon:hits:shots:kills:deaths on %s", word)}
+		}
+	}
+	return stats, nil
+}
+
+// weapon returns the accuracy of the player with a weapon family.
+func (pi *PlayersInfo) weapon(family string) *WeaponAccuracy {
+	if pi.Weapons == nil {
+		pi.Weapons = make(map[string]*WeaponAccuracy)
+	}
+	a, ok := pi.Weapons[family]
+	if !ok {
+		a = &WeaponAccuracy{}
+		pi.Weapons[family] = a
+	}
+	return a
+}
+
+func isHeadshot(location string) bool {
+	location = strings.ToLower(location)
+	return strings.Contains(location, "head") || strings.Contains(location, "helmet")
+}
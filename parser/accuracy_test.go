@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWeaponStats(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected any
+		err      error
+	}{
+		"Valid": {
+			input: "5:00 Weapon_Stats: 2 MachineGun:12:80:1:0 R.Launcher:5:14:2:1 Given:640 Recvd:310 Armor:50 Health:25",
+			expected: WeaponStats{
+				ClientId: 2,
+				Weapons: map[string]WeaponAccuracy{
+					"machinegun":     {Hits: 12, Shots: 80, Kills: 1},
+					"rocketlauncher": {Hits: 5, Shots: 14, Kills: 2, Deaths: 1},
+				},
+				DamageGiven:    640,
+				DamageReceived: 310,
+			},
+		},
+		"InvalidFields": {
+			input: "5:00 Weapon_Stats: 2 MachineGun:12:80",
+			err:   &SyntaxError{LHWeaponStats, "expecting weapon:hits:shots:kills:deaths on MachineGun:12:80"},
+		},
+		"InvalidInteger": {
+			input: "5:00 Weapon_Stats: 2 MachineGun:12:x:1:0",
+			err:   &SyntaxError{LHWeaponStats, "expecting integers on MachineGun:12:x:1:0"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			event, err := DialectOSP.ParseLine(test.input)
+			if !reflect.DeepEqual(err, test.err) {
+				t.Fatalf("Expected %v, but got %v", test.err, err)
+			}
+			if err == nil && !reflect.DeepEqual(event.Data, test.expected) {
+				t.Errorf("Expected %+v, but got %+v", test.expected, event.Data)
+			}
+		})
+	}
+}
+
+func TestGetGameWeaponStats(t *testing.T) {
+	input := `  0:00 InitGame: \gamename\osp\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  4:00 Weapon_Stats: 2 MachineGun:2:10:0:0 Given:100 Recvd:50
+  5:00 Weapon_Stats: 2 MachineGun:12:80:1:0 Railgun:3:4:3:0 Given:640 Recvd:310
+  5:00 Weapon_Stats: 7 MachineGun:1:10:0:0 Given:10 Recvd:0
+  5:00 ShutdownGame:
+`
+	// the stats of client 7, which is not on the game, are skipped
+	gs := newTestScanner(input, EPLenient)
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil || game == nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	pi := game.PlayersInfoById[2]
+	expected := map[string]*WeaponAccuracy{
+		"machinegun": {Shots: 80, Hits: 12, Kills: 1},
+		"railgun":    {Shots: 4, Hits: 3, Kills: 3},
+	}
+	if !reflect.DeepEqual(pi.Weapons, expected) {
+		t.Errorf("Expected %v, but got %v", expected, pi.Weapons)
+	}
+	if pi.DamageGiven != 640 || pi.DamageReceived != 310 {
+		t.Errorf("Expected %v and %v, but got %v and %v", 640, 310, pi.DamageGiven, pi.DamageReceived)
+	}
+	if accuracy := pi.Weapons["railgun"].Accuracy(); accuracy != 0.75 {
+		t.Errorf("Expected %v, but got %v", 0.75, accuracy)
+	}
+}
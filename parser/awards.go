@@ -0,0 +1,291 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// AwardStats are the stats of a player that awards are given by, keyed by
+// the names on AwardStatNames.
+type AwardStats map[string]float64
+
+// AwardStatNames are the stats an AwardRule can rank the players by.
+var AwardStatNames = []string{
+	"score",
+	"kills",
+	"deaths",
+	"suicides",
+	"team-kills",
+	"environment-deaths",
+	"gauntlet-kills",
+	// revenge-kills are the kills of the player that killed the killer the
+	// most so far on the game.
+	"revenge-kills",
+	"best-streak",
+	"captures",
+	"damage-given",
+	"wins",
+	"games",
+}
+
+// AwardScopeGame and AwardScopeSeason are where an award is given: on each
+// game or on the season made of every game of a summary.
+const (
+	AwardScopeGame   = "game"
+	AwardScopeSeason = "season"
+)
+
+// AwardRule gives an award to the players with the highest value of a stat,
+// or the lowest with Lowest. Ties share the award.
+type AwardRule struct {
+	Name        string
+	Description string
+	Stat        string
+	Lowest      bool `json:",omitempty"`
+	// Min and Max bound the values that win the award, e.g. Max 0 deaths.
+	Min *float64 `json:",omitempty"`
+	Max *float64 `json:",omitempty"`
+	// Require are the lowest values of other stats a player needs to be
+	// ranked, e.g. 1 kill for an award for not dying.
+	Require map[string]float64 `json:",omitempty"`
+	// Scope is AwardScopeGame, AwardScopeSeason or empty for both.
+	Scope string `json:",omitempty"`
+}
+
+// Award is an award won by a player, Value is the stat of the rule.
+type Award struct {
+	Name        string
+	Description string
+	Player      string
+	Value       float64
+}
+
+func bound(value float64) *float64 {
+	return &value
+}
+
+func DefaultAwardRules() []AwardRule {
+	return []AwardRule{
+		{Name: "MVP", Description: "Highest score", Stat: "score", Min: bound(1)},
+		{Name: "Most Deaths to the Environment", Description: "Killed the most by the map", Stat: "environment-deaths", Min: bound(1)},
+		{Name: "Pacifist", Description: "Played without killing anyone", Stat: "kills", Lowest: true, Max: bound(0), Scope: AwardScopeGame},
+		{Name: "Revenge", Description: "Killed the nemesis the most times", Stat: "revenge-kills", Min: bound(1)},
+		{Name: "Humiliation", Description: "Most gauntlet kills", Stat: "gauntlet-kills", Min: bound(1)},
+		{Name: "Untouchable", Description: "Killed without ever dying", Stat: "deaths", Lowest: true, Max: bound(0), Require: map[string]float64{"kills": 1}, Scope: AwardScopeGame},
+		{Name: "Champion", Description: "Most games won", Stat: "wins", Min: bound(1), Scope: AwardScopeSeason},
+	}
+}
+
+// LoadAwardRules reads the award rules from a json file with a list of
+// rules, they replace the default ones.
+func LoadAwardRules(path string) ([]AwardRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []AwardRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+func isAwardStat(stat string) bool {
+	for _, name := range AwardStatNames {
+		if name == stat {
+			return true
+		}
+	}
+	return false
+}
+
+func (r AwardRule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("award without name")
+	}
+	if !isAwardStat(r.Stat) {
+		return fmt.Errorf("unknown stat on award %s: %s", r.Name, r.Stat)
+	}
+	for stat := range r.Require {
+		if !isAwardStat(stat) {
+			return fmt.Errorf("unknown stat on award %s: %s", r.Name, stat)
+		}
+	}
+	if r.Scope != "" && r.Scope != AwardScopeGame && r.Scope != AwardScopeSeason {
+		return fmt.Errorf("unknown scope on award %s: %s", r.Name, r.Scope)
+	}
+	return nil
+}
+
+// AwardStats returns the stats of the players of the game by username,
+// adding up the connections of players that reconnected. Players that did
+// not score, kill or die are left out.
+func (game *Game) AwardStats() map[string]AwardStats {
+	statsByName := make(map[string]AwardStats)
+	add := func(info *PlayersInfo) {
+		if info.Id == 0 || info.Id == UnknownPlayerId || info.Username == "" {
+			return
+		}
+		if info.Score == 0 && info.KillCount == 0 && info.DeathCount == 0 {
+			return
+		}
+		stats, ok := statsByName[info.Username]
+		if !ok {
+			stats = AwardStats{"games": 1}
+			statsByName[info.Username] = stats
+		}
+		stats["score"] += float64(info.Score)
+		stats["kills"] += float64(info.KillCount)
+		stats["deaths"] += float64(info.DeathCount)
+		stats["suicides"] += float64(info.SuicideCount)
+		stats["team-kills"] += float64(info.TeamKillCount)
+		stats["captures"] += float64(info.CTF.Captures)
+		stats["damage-given"] += float64(info.DamageGiven)
+		if streak := float64(info.BestKillStreak); streak > stats["best-streak"] {
+			stats["best-streak"] = streak
+		}
+	}
+	for _, info := range game.PlayersInfoById {
+		add(info)
+	}
+	for _, info := range game.DisconnectedPlayers {
+		add(info)
+	}
+
+	// killedBy counts the kills of each player by each other player so far
+	killedBy := make(map[string]map[string]int)
+	for _, kill := range game.Kills {
+		if kill.KillerId == 0 {
+			if m, _ := LookupMeans(kill.Means); m.Class == MCEnvironment {
+				if stats, ok := statsByName[kill.Victim]; ok {
+					stats["environment-deaths"]++
+				}
+			}
+			continue
+		}
+		if kill.Killer == kill.Victim || kill.TeamKill {
+			continue
+		}
+		if stats, ok := statsByName[kill.Killer]; ok {
+			if m, _ := LookupMeans(kill.Means); m.Family == "gauntlet" {
+				stats["gauntlet-kills"]++
+			}
+			if isNemesis(killedBy[kill.Killer], kill.Victim) {
+				stats["revenge-kills"]++
+			}
+		}
+		if killedBy[kill.Victim] == nil {
+			killedBy[kill.Victim] = make(map[string]int)
+		}
+		killedBy[kill.Victim][kill.Killer]++
+	}
+
+	// on team games every player on the winning team at the end wins
+	result := game.Result()
+	winners := make(map[string]bool)
+	if result.Winner != "" {
+		winners[result.Winner] = true
+	}
+	if result.WinningTeam != "" {
+		for _, info := range game.PlayersInfoById {
+			if info.Team.String() == result.WinningTeam {
+				winners[info.Username] = true
+			}
+		}
+	}
+	for name := range winners {
+		if stats, ok := statsByName[name]; ok {
+			stats["wins"]++
+		}
+	}
+	return statsByName
+}
+
+// isNemesis tells if player has the most kills on killedBy.
+func isNemesis(killedBy map[string]int, player string) bool {
+	count := killedBy[player]
+	if count == 0 {
+		return false
+	}
+	for _, other := range killedBy {
+		if other > count {
+			return false
+		}
+	}
+	return true
+}
+
+// Add sums the stats of other into s, keeping the best of best-streak.
+func (s AwardStats) Add(other AwardStats) {
+	for stat, value := range other {
+		if stat == "best-streak" {
+			if value > s[stat] {
+				s[stat] = value
+			}
+			continue
+		}
+		s[stat] += value
+	}
+}
+
+// GiveAwards returns the awards of the rules with the given scope, ordered as
+// the rules and then by player.
+func GiveAwards(rules []AwardRule, scope string, statsByName map[string]AwardStats) []Award {
+	players := make([]string, 0, len(statsByName))
+	for name := range statsByName {
+		players = append(players, name)
+	}
+	sort.Strings(players)
+
+	var awards []Award
+	for _, rule := range rules {
+		if rule.Scope != "" && rule.Scope != scope {
+			continue
+		}
+		var winners []string
+		var best float64
+		for _, player := range players {
+			stats := statsByName[player]
+			if !rule.eligible(stats) {
+				continue
+			}
+			value := stats[rule.Stat]
+			better := value > best
+			if rule.Lowest {
+				better = value < best
+			}
+			if len(winners) == 0 || better {
+				winners, best = []string{player}, value
+			} else if value == best {
+				winners = append(winners, player)
+			}
+		}
+		for _, player := range winners {
+			awards = append(awards, Award{Name: rule.Name, Description: rule.Description, Player: player, Value: best})
+		}
+	}
+	return awards
+}
+
+func (r AwardRule) eligible(stats AwardStats) bool {
+	value := stats[r.Stat]
+	if r.Min != nil && value < *r.Min {
+		return false
+	}
+	if r.Max != nil && value > *r.Max {
+		return false
+	}
+	for stat, min := range r.Require {
+		if stats[stat] < min {
+			return false
+		}
+	}
+	return true
+}
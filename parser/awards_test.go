@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGameAwards(t *testing.T) {
+	input := `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Mocinha\t\0
+  0:02 Kill: 3 2 10: Zeh killed Isgalamido by MOD_RAILGUN
+  0:03 Kill: 2 3 2: Isgalamido killed Zeh by MOD_GAUNTLET
+  0:04 Kill: 2 3 2: Isgalamido killed Zeh by MOD_GAUNTLET
+  0:05 Kill: 1022 4 19: <world> killed Mocinha by MOD_FALLING
+  0:06 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	game, _, err := gs.GetGame()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stats := game.AwardStats()
+	// Zeh killed Isgalamido first, so both kills of Zeh are revenges
+	if stats["Isgalamido"]["revenge-kills"] != 2 || stats["Zeh"]["revenge-kills"] != 0 || stats["Isgalamido"]["gauntlet-kills"] != 2 {
+		t.Errorf("Unexpected stats %v", stats["Isgalamido"])
+	}
+	if stats["Mocinha"]["environment-deaths"] != 1 || stats["Isgalamido"]["wins"] != 1 {
+		t.Errorf("Unexpected stats %v", stats)
+	}
+
+	expected := []Award{
+		{Name: "MVP", Description: "Highest score", Player: "Isgalamido", Value: 2},
+		{Name: "Most Deaths to the Environment", Description: "Killed the most by the map", Player: "Mocinha", Value: 1},
+		{Name: "Pacifist", Description: "Played without killing anyone", Player: "Mocinha", Value: 0},
+		{Name: "Revenge", Description: "Killed the nemesis the most times", Player: "Isgalamido", Value: 2},
+		{Name: "Humiliation", Description: "Most gauntlet kills", Player: "Isgalamido", Value: 2},
+	}
+	if !reflect.DeepEqual(game.Awards, expected) {
+		t.Errorf("Expected %+v, but got %+v", expected, game.Awards)
+	}
+}
+
+func TestTeamGameAwardStats(t *testing.T) {
+	input := `  0:00 InitGame: \g_gametype\3\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\1
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\1
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Mocinha\t\2
+  0:02 Kill: 2 4 10: Isgalamido killed Mocinha by MOD_RAILGUN
+  0:03 Kill: 3 2 2: Zeh killed Isgalamido by MOD_GAUNTLET
+  0:04 Kill: 4 3 10: Mocinha killed Zeh by MOD_RAILGUN
+  0:05 red:1  blue:1
+  0:05 red:2  blue:1
+  0:06 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	game, _, err := gs.GetGame()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stats := game.AwardStats()
+	if stats["Isgalamido"]["wins"] != 1 || stats["Zeh"]["wins"] != 1 || stats["Mocinha"]["wins"] != 0 {
+		t.Errorf("Expected a win for each player of the red team, but got %v", stats)
+	}
+	if stats["Zeh"]["gauntlet-kills"] != 0 {
+		t.Errorf("Expected the gauntlet team kill to be left out, but got %v", stats["Zeh"])
+	}
+}
+
+func TestGiveAwards(t *testing.T) {
+	stats := map[string]AwardStats{
+		"Isgalamido": {"kills": 10, "deaths": 0, "wins": 2},
+		"Zeh":        {"kills": 10, "deaths": 3, "wins": 2},
+		"Mocinha":    {"kills": 0, "deaths": 0},
+	}
+	tests := map[string]struct {
+		rule     AwardRule
+		scope    string
+		expected []string
+	}{
+		"Highest": {
+			rule:     AwardRule{Name: "Killer", Stat: "kills"},
+			scope:    AwardScopeGame,
+			expected: []string{"Isgalamido", "Zeh"},
+		},
+		"Lowest": {
+			rule:     AwardRule{Name: "Untouchable", Stat: "deaths", Lowest: true, Max: bound(0), Require: map[string]float64{"kills": 1}},
+			scope:    AwardScopeGame,
+			expected: []string{"Isgalamido"},
+		},
+		"Bounded": {
+			rule:     AwardRule{Name: "Butcher", Stat: "kills", Min: bound(11)},
+			scope:    AwardScopeGame,
+			expected: nil,
+		},
+		"OtherScope": {
+			rule:     AwardRule{Name: "Champion", Stat: "wins", Scope: AwardScopeSeason},
+			scope:    AwardScopeGame,
+			expected: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var winners []string
+			for _, a := range GiveAwards([]AwardRule{test.rule}, test.scope, stats) {
+				winners = append(winners, a.Player)
+			}
+			if !reflect.DeepEqual(winners, test.expected) {
+				t.Errorf("Expected %v, but got %v", test.expected, winners)
+			}
+		})
+	}
+}
+
+func TestLoadAwardRules(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	os.WriteFile(valid, []byte(`[{"Name": "Butcher", "Stat": "kills", "Min": 30, "Scope": "season"}]`), 0o644)
+	rules, err := LoadAwardRules(valid)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []AwardRule{{Name: "Butcher", Stat: "kills", Min: bound(30), Scope: AwardScopeSeason}}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected %+v, but got %+v", expected, rules)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`[{"Name": "Butcher", "Stat": "frags"}]`), 0o644)
+	if _, err := LoadAwardRules(invalid); err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
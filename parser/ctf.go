@@ -0,0 +1,315 @@
+package parser
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FlagAction is what a player did with a flag on a CTF game.
+type FlagAction uint8
+
+const (
+	FAPickup FlagAction = iota
+	FADrop
+	FAReturn
+	FACapture
+	// FAAssist is a return or a kill of the enemy flag carrier shortly before
+	// a capture of the team.
+	FAAssist
+)
+
+// ctfAssistTimeout is how long a return or a kill of the flag carrier counts
+// as an assist for a capture, as on Quake 3.
+const ctfAssistTimeout = 10 * time.Second
+
+// ctfFlagReturnTime is how long a dropped flag stays on the ground before it
+// returns to its base by itself, as on Quake 3.
+const ctfFlagReturnTime = 30 * time.Second
+
+// FlagEvent is a flag action of a player. Quake 3 logs the flags touched as
+// Item lines, team_CTF_redflag and team_CTF_blueflag, so the action is
+// inferred from the team of the player and the state of the flags. OpenArena
+// logs the actions on CTF lines.
+type FlagEvent struct {
+	ClientId int
+	Username string
+	// Flag is the team that owns the flag.
+	Flag   Team
+	Action FlagAction
+}
+
+// CTFStats are the flag statistics of a player.
+type CTFStats struct {
+	FlagPickups int
+	Captures    int
+	Returns     int
+	Assists     int
+	// FlagCarrierKills are the kills of enemies carrying a flag.
+	FlagCarrierKills int
+	// CarryTime is the time the player held the enemy flag until it was
+	// captured or dropped, or the game ended.
+	CarryTime time.Duration
+	// LastReturn and LastCarrierKill are kept to find the assists.
+	LastReturn      string `json:",omitempty"`
+	LastCarrierKill string `json:",omitempty"`
+}
+
+// FlagState is where a flag is. A flag neither carried nor dropped is at its
+// base.
+type FlagState struct {
+	Carried    bool
+	CarrierId  int
+	PickupTime string
+	Dropped    bool
+	DropTime   string `json:",omitempty"`
+}
+
+// CTF is a flag action logged by OpenArena:
+//
+//	CTF: <client> <flag team> <action>: <name> captured the BLUE flag!
+//
+// The actions are 0 for a pickup, 1 for a capture and 2 for a return, other
+// actions are read as ModEvent.
+type CTF struct {
+	ClientId int
+	// Flag is the team that owns the flag.
+	Flag   Team
+	Action FlagAction
+}
+
+// parseCTF reads a CTF line. known is false for the actions without a
+// FlagAction, such as the kills of flag carriers, already found on the kills.
+func parseCTF(words []string) (ctf CTF, known bool, err error) {
+	if len(words) < 5 {
+		return CTF{}, false, &SyntaxError{LHCTF, "expecting 5 or more words on log line"}
+	}
+	clientId, err := strconv.Atoi(words[2])
+	if err != nil {
+		return CTF{}, false, &SyntaxError{LHCTF, "expecting clientId to be an integer"}
+	}
+	team, err := strconv.Atoi(words[3])
+	if err != nil || (Team(team) != TMRed && Team(team) != TMBlue) {
+		return CTF{}, false, &SyntaxError{LHCTF, "expecting team to be 1 or 2"}
+	}
+	action, err := strconv.Atoi(strings.TrimSuffix(words[4], ":"))
+	if err != nil {
+		return CTF{}, false, &SyntaxError{LHCTF, "expecting action to be an integer"}
+	}
+	ctf = CTF{ClientId: clientId, Flag: Team(team)}
+	switch action {
+	case 0:
+		ctf.Action = FAPickup
+	case 1:
+		ctf.Action = FACapture
+	case 2:
+		ctf.Action = FAReturn
+	default:
+		return ctf, false, nil
+	}
+	return ctf, true, nil
+}
+
+// CTFState is the state of the flags of a CTF game.
+type CTFState struct {
+	Red  FlagState
+	Blue FlagState
+}
+
+// IsCTF reports if the game is a capture the flag game.
+func (game *Game) IsCTF() bool {
+	return game.ServerConfig["g_gametype"] == "4" || game.CTF != nil
+}
+
+// flagOf returns the team of a flag classname, such as team_CTF_redflag.
+func flagOf(classname string) (Team, bool) {
+	switch strings.ToLower(classname) {
+	case "team_ctf_redflag":
+		return TMRed, true
+	case "team_ctf_blueflag":
+		return TMBlue, true
+	}
+	return TMFree, false
+}
+
+func enemyTeam(team Team) Team {
+	if team == TMRed {
+		return TMBlue
+	}
+	return TMRed
+}
+
+func (game *Game) flag(team Team) *FlagState {
+	if game.CTF == nil {
+		game.CTF = &CTFState{}
+	}
+	if team == TMRed {
+		return &game.CTF.Red
+	}
+	return &game.CTF.Blue
+}
+
+// touchFlag applies a flag touched by a player. The enemy flag is picked up,
+// the own flag is returned when dropped, or captures the enemy flag carried
+// by the player.
+func (gs *GameScanner) touchFlag(game *Game, time string, pi *PlayersInfo, flag Team) {
+	if pi.Team != TMRed && pi.Team != TMBlue {
+		return
+	}
+	game.returnFlags(time)
+	if flag != pi.Team {
+		gs.pickupFlag(game, time, pi, flag)
+		return
+	}
+
+	state := game.flag(flag)
+	enemyFlag := game.flag(enemyTeam(pi.Team))
+	switch {
+	case state.Dropped:
+		gs.returnFlag(game, time, pi, flag)
+	case enemyFlag.Carried && enemyFlag.CarrierId == pi.Id:
+		gs.captureFlag(game, time, pi, enemyTeam(pi.Team))
+	}
+}
+
+// applyCTF applies a flag action logged on a CTF line.
+func (gs *GameScanner) applyCTF(game *Game, time string, pi *PlayersInfo, ctf CTF) {
+	game.returnFlags(time)
+	switch ctf.Action {
+	case FAPickup:
+		gs.pickupFlag(game, time, pi, ctf.Flag)
+	case FACapture:
+		gs.captureFlag(game, time, pi, ctf.Flag)
+	case FAReturn:
+		gs.returnFlag(game, time, pi, ctf.Flag)
+	}
+}
+
+func (gs *GameScanner) pickupFlag(game *Game, time string, pi *PlayersInfo, flag Team) {
+	*game.flag(flag) = FlagState{Carried: true, CarrierId: pi.Id, PickupTime: time}
+	pi.CTF.FlagPickups++
+	gs.addFlagEvent(pi, flag, FAPickup)
+}
+
+func (gs *GameScanner) returnFlag(game *Game, time string, pi *PlayersInfo, flag Team) {
+	*game.flag(flag) = FlagState{}
+	pi.CTF.Returns++
+	pi.CTF.LastReturn = time
+	gs.addFlagEvent(pi, flag, FAReturn)
+}
+
+// captureFlag applies the capture of flag by pi, giving an assist to the
+// teammates that returned their flag or killed the enemy carrier shortly
+// before.
+func (gs *GameScanner) captureFlag(game *Game, time string, pi *PlayersInfo, flag Team) {
+	state := game.flag(flag)
+	if state.Carried && state.CarrierId == pi.Id {
+		pi.CTF.CarryTime += elapsed(state.PickupTime, time)
+	}
+	*state = FlagState{}
+	pi.CTF.Captures++
+	gs.addFlagEvent(pi, flag, FACapture)
+
+	ids := make([]int, 0, len(game.PlayersInfoById))
+	for id := range game.PlayersInfoById {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		mate := game.PlayersInfoById[id]
+		if mate == pi || mate.Team != pi.Team {
+			continue
+		}
+		if withinAssist(mate.CTF.LastReturn, time) || withinAssist(mate.CTF.LastCarrierKill, time) {
+			mate.CTF.Assists++
+			gs.addFlagEvent(mate, flag, FAAssist)
+		}
+	}
+}
+
+// returnFlags moves back to their base the flags dropped for longer than
+// ctfFlagReturnTime, the server does not log it.
+func (game *Game) returnFlags(time string) {
+	if game.CTF == nil {
+		return
+	}
+	for _, state := range []*FlagState{&game.CTF.Red, &game.CTF.Blue} {
+		if state.Dropped && elapsed(state.DropTime, time) >= ctfFlagReturnTime {
+			*state = FlagState{}
+		}
+	}
+}
+
+// closeCarries adds the time held by the carriers still holding a flag at the
+// end of the game. The pickup time moves to the end, so a reopened game keeps
+// counting from there.
+func (game *Game) closeCarries() {
+	if game.CTF == nil {
+		return
+	}
+	for _, state := range []*FlagState{&game.CTF.Red, &game.CTF.Blue} {
+		if !state.Carried {
+			continue
+		}
+		if pi, ok := game.PlayersInfoById[state.CarrierId]; ok {
+			pi.CTF.CarryTime += elapsed(state.PickupTime, game.EndTime)
+		}
+		state.PickupTime = game.EndTime
+	}
+}
+
+// dropFlag drops the flag carried by victim, if any. killer is nil when the
+// player left the game.
+func (gs *GameScanner) dropFlag(game *Game, time string, killer *PlayersInfo, victim *PlayersInfo) {
+	if game.CTF == nil {
+		return
+	}
+	for _, flag := range []Team{TMRed, TMBlue} {
+		state := game.flag(flag)
+		if !state.Carried || state.CarrierId != victim.Id {
+			continue
+		}
+		victim.CTF.CarryTime += elapsed(state.PickupTime, time)
+		*state = FlagState{Dropped: true, DropTime: time}
+		gs.addFlagEvent(victim, flag, FADrop)
+		if killer != nil && killer != victim && killer.Id != 0 && killer.Id != UnknownPlayerId && killer.Team != victim.Team {
+			killer.CTF.FlagCarrierKills++
+			killer.CTF.LastCarrierKill = time
+		}
+	}
+}
+
+func (gs *GameScanner) addFlagEvent(pi *PlayersInfo, flag Team, action FlagAction) {
+	gs.flagEvents = append(gs.flagEvents, FlagEvent{
+		ClientId: pi.Id,
+		Username: pi.Username,
+		Flag:     flag,
+		Action:   action,
+	})
+}
+
+// elapsed is the time between two log times, zero when they can not be read.
+func elapsed(from string, to string) time.Duration {
+	start, err := ParseTime(from)
+	if err != nil {
+		return 0
+	}
+	end, err := ParseTime(to)
+	if err != nil || end < start {
+		return 0
+	}
+	return end - start
+}
+
+func withinAssist(last string, now string) bool {
+	l, err := ParseTime(last)
+	if err != nil {
+		return false
+	}
+	n, err := ParseTime(now)
+	if err != nil {
+		return false
+	}
+	return n >= l && n-l <= ctfAssistTimeout
+}
@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type flagRecorder struct {
+	BaseHandler
+	events []string
+}
+
+func (h *flagRecorder) OnFlag(game *Game, time string, flag FlagEvent) {
+	h.events = append(h.events, time+" "+flag.Username+" "+flag.Action.String()+" "+flag.Flag.String())
+}
+
+func TestCTF(t *testing.T) {
+	input := `  0:00 InitGame: \mapname\q3ctf1\g_gametype\4
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\1
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\1
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Mocinha\t\2
+  0:02 Item: 4 team_CTF_redflag
+  0:05 Kill: 3 4 10: Zeh killed Mocinha by MOD_RAILGUN
+  0:06 Item: 3 team_CTF_redflag
+  0:10 Item: 2 team_CTF_blueflag
+  0:30 Item: 2 team_CTF_redflag
+  0:31 Item: 4 team_CTF_redflag
+  0:35 ClientDisconnect: 4
+  0:40 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	recorder := &flagRecorder{}
+	gs.Register(recorder)
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	if !game.IsCTF() {
+		t.Errorf("Expected a CTF game")
+	}
+
+	expected := []string{
+		"0:02 Mocinha pickup red",
+		"0:05 Mocinha drop red",
+		"0:06 Zeh return red",
+		"0:10 Isgalamido pickup blue",
+		"0:30 Isgalamido capture blue",
+		"0:31 Mocinha pickup red",
+		"0:35 Mocinha drop red",
+	}
+	if !reflect.DeepEqual(recorder.events, expected) {
+		t.Errorf("Expected %v, but got %v", expected, recorder.events)
+	}
+
+	isgalamido := game.PlayersInfoById[2].CTF
+	if isgalamido.Captures != 1 || isgalamido.FlagPickups != 1 || isgalamido.CarryTime != 20*time.Second {
+		t.Errorf("Expected 1 capture, 1 pickup and 20s carrying, but got %+v", isgalamido)
+	}
+	// the return was 24 seconds before the capture, too late for an assist
+	zeh := game.PlayersInfoById[3].CTF
+	if zeh.Returns != 1 || zeh.FlagCarrierKills != 1 || zeh.Assists != 0 {
+		t.Errorf("Expected 1 return, 1 carrier kill and no assist, but got %+v", zeh)
+	}
+	mocinha := game.DisconnectedPlayers[0].CTF
+	if mocinha.FlagPickups != 2 || mocinha.CarryTime != 7*time.Second {
+		t.Errorf("Expected 2 pickups and 7s carrying, but got %+v", mocinha)
+	}
+	if !game.CTF.Red.Dropped || game.CTF.Blue != (FlagState{}) {
+		t.Errorf("Expected red flag dropped and blue flag at base, but got %+v", game.CTF)
+	}
+}
+
+func TestCTFAssist(t *testing.T) {
+	input := `  0:00 InitGame: \mapname\q3ctf1\g_gametype\4
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\1
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\1
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Mocinha\t\2
+  0:02 Item: 2 team_CTF_blueflag
+  0:03 Item: 4 team_CTF_redflag
+  0:04 Kill: 3 4 10: Zeh killed Mocinha by MOD_RAILGUN
+  0:08 Item: 2 team_CTF_redflag
+  0:09 Item: 2 team_CTF_redflag
+  0:10 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	// the first touch returns the dropped flag, the second captures
+	isgalamido := game.PlayersInfoById[2].CTF
+	if isgalamido.Returns != 1 || isgalamido.Captures != 1 {
+		t.Errorf("Expected 1 return and 1 capture, but got %+v", isgalamido)
+	}
+	if zeh := game.PlayersInfoById[3].CTF; zeh.Assists != 1 {
+		t.Errorf("Expected 1 assist, but got %+v", zeh)
+	}
+}
+
+func TestCTFFlagReturnAndCarryAtEnd(t *testing.T) {
+	input := `  0:00 InitGame: \mapname\q3ctf1\g_gametype\4
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\1
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\1
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Mocinha\t\2
+  0:02 Item: 4 team_CTF_redflag
+  0:05 Kill: 3 4 10: Zeh killed Mocinha by MOD_RAILGUN
+  0:10 Item: 2 team_CTF_blueflag
+  0:40 Item: 2 team_CTF_redflag
+  0:45 Item: 4 team_CTF_redflag
+  0:50 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	// the red flag returned by itself 30s after the drop, so the touch of
+	// Isgalamido captures
+	isgalamido := game.PlayersInfoById[2].CTF
+	if isgalamido.Returns != 0 || isgalamido.Captures != 1 || isgalamido.CarryTime != 30*time.Second {
+		t.Errorf("Expected 1 capture after 30s carrying and no return, but got %+v", isgalamido)
+	}
+	if game.CTF.Blue != (FlagState{}) {
+		t.Errorf("Expected blue flag at base, but got %+v", game.CTF.Blue)
+	}
+	// Mocinha still holds the red flag when the game ends
+	if mocinha := game.PlayersInfoById[4].CTF; mocinha.CarryTime != 8*time.Second {
+		t.Errorf("Expected 8s carrying, but got %+v", mocinha)
+	}
+}
+
+func TestCTFLines(t *testing.T) {
+	input := `  0:00 InitGame: \mapname\oa_ctf2\g_gametype\4\gamename\baseoa
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\1
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Mocinha\t\2
+  0:02 Item: 2 team_CTF_blueflag
+  0:02 CTF: 2 2 0: Isgalamido got the BLUE flag!
+  0:12 Item: 2 team_CTF_redflag
+  0:12 CTF: 2 2 1: Isgalamido captured the BLUE flag!
+  0:13 CTF: 4 1 3: Mocinha fragged Isgalamido's flag carrier!
+  0:20 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	recorder := &flagRecorder{}
+	gs.Register(recorder)
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	expected := []string{
+		"0:02 Isgalamido pickup blue",
+		"0:12 Isgalamido capture blue",
+	}
+	if !reflect.DeepEqual(recorder.events, expected) {
+		t.Errorf("Expected %v, but got %v", expected, recorder.events)
+	}
+	isgalamido := game.PlayersInfoById[2].CTF
+	if isgalamido.FlagPickups != 1 || isgalamido.Captures != 1 || isgalamido.CarryTime != 10*time.Second {
+		t.Errorf("Expected 1 pickup and 1 capture after 10s carrying, but got %+v", isgalamido)
+	}
+}
+
+func TestFlagActionText(t *testing.T) {
+	for _, action := range []FlagAction{FAPickup, FADrop, FAReturn, FACapture, FAAssist} {
+		data, err := json.Marshal(action)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		var read FlagAction
+		if err := json.Unmarshal(data, &read); err != nil || read != action {
+			t.Errorf("Expected %v, but got %v (error %v)", action, read, err)
+		}
+	}
+	var read FlagAction
+	if err := json.Unmarshal([]byte(`"steal"`), &read); err == nil {
+		t.Errorf("Expected an error for an unknown action")
+	}
+}
@@ -0,0 +1,255 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect is the log grammar of a Quake 3 mod: the headers it logs besides
+// the ones of baseq3, its means of death and its team codes. The dialect of
+// a game is detected from the gamename and version of its InitGame.
+type Dialect struct {
+	Name string
+	// GameNames are the gamename values of the mod, compared ignoring case.
+	GameNames []string
+	// Versions are parts of the version value that identify the mod, for
+	// servers that keep the gamename of baseq3.
+	Versions []string
+	// Headers are the headers logged by the mod that baseq3 does not log.
+	// Headers without a parser on this package are read as LHMod events.
+	Headers map[string]LogHeader
+	// Means is the catalog of means of death, the one of baseq3 when empty.
+	Means []MeansOfDeath
+	// Teams maps the values of the t userinfo key that are not a team number.
+	Teams map[string]Team
+	// HitWeapons maps the weapon ids of Hit lines to weapon families.
+	HitWeapons map[int]string
+}
+
+// DialectBaseq3 is the grammar of ioquake3 with the baseq3 game code, used
+// when no other dialect matches.
+var DialectBaseq3 = &Dialect{
+	Name:      "baseq3",
+	GameNames: []string{"baseq3"},
+}
+
+// DialectMissionpack is the grammar of ioquake3 with the Team Arena game
+// code, its means of death have other ids.
+var DialectMissionpack = &Dialect{
+	Name:      "missionpack",
+	GameNames: []string{"missionpack"},
+	Means:     missionpackMeans,
+}
+
+var DialectOpenArena = &Dialect{
+	Name:      "openarena",
+	GameNames: []string{"baseoa"},
+	Versions:  []string{"ioq3+oa", "openarena"},
+	Means:     missionpackMeans,
+	Headers: map[string]LogHeader{
+		"Award:":       LHMod,
+		"Challenge:":   LHMod,
+		"CTF:":         LHCTF,
+		"PlayerScore:": LHMod,
+		"Warmup:":      LHMod,
+		"Info:":        LHMod,
+	},
+}
+
+var DialectCPMA = &Dialect{
+	Name:      "cpma",
+	GameNames: []string{"cpma"},
+	Headers: map[string]LogHeader{
+		"Weapon_Stats:": LHWeaponStats,
+	},
+}
+
+var DialectOSP = &Dialect{
+	Name:      "osp",
+	GameNames: []string{"osp"},
+	Headers: map[string]LogHeader{
+		"Weapon_Stats:": LHWeaponStats,
+	},
+}
+
+var DialectUrbanTerror = &Dialect{
+	Name:      "urt",
+	GameNames: []string{"q3ut4", "q3urt41", "q3urt42", "q3urt43"},
+	Versions:  []string{"urt"},
+	Headers: map[string]LogHeader{
+		"Hit:":              LHHit,
+		"Flag:":             LHMod,
+		"FlagCaptureTime:":  LHMod,
+		"Radio:":            LHMod,
+		"Assist:":           LHMod,
+		"ClientUserinfo:":   LHMod,
+		"ClientSpawn:":      LHMod,
+		"InitRound:":        LHMod,
+		"SurvivorWinner:":   LHMod,
+		"AccountValidated:": LHMod,
+		"Warmup:":           LHMod,
+		"sayteam:":          LHMod,
+		"tell:":             LHMod,
+	},
+	Means: urbanTerrorMeans,
+	Teams: map[string]Team{"FREE": TMFree, "RED": TMRed, "BLUE": TMBlue, "SPECTATOR": TMSpectator},
+	HitWeapons: map[int]string{
+		1: "knife", 2: "beretta", 3: "deagle", 4: "spas", 5: "mp5k", 6: "ump45",
+		8: "lr300", 9: "g36", 10: "psg1", 14: "sr8", 15: "ak103", 17: "negev",
+		19: "m4", 20: "glock", 21: "colt1911", 22: "mac11", 23: "frf1",
+		24: "benelli", 25: "p90", 26: "magnum", 29: "kick", 30: "knife",
+	},
+}
+
+// urbanTerrorMeans are the means of death of Urban Terror 4.
+var urbanTerrorMeans = []MeansOfDeath{
+	{1, "MOD_WATER", "water", MCEnvironment, "Drowned"},
+	{2, "MOD_SLIME", "slime", MCEnvironment, "Slime"},
+	{3, "MOD_LAVA", "lava", MCEnvironment, "Lava"},
+	{4, "MOD_CRUSH", "crush", MCEnvironment, "Crushed"},
+	{5, "MOD_TELEFRAG", "telefrag", MCOther, "Telefrag"},
+	{6, "MOD_FALLING", "falling", MCEnvironment, "Falling"},
+	{7, "MOD_SUICIDE", "suicide", MCSelf, "Suicide"},
+	{8, "MOD_TARGET_LASER", "target_laser", MCEnvironment, "Laser"},
+	{9, "MOD_TRIGGER_HURT", "trigger_hurt", MCEnvironment, "Map hazard"},
+	{10, "MOD_CHANGE_TEAM", "change_team", MCSelf, "Team change"},
+	{12, "UT_MOD_KNIFE", "knife", MCWeapon, "Knife"},
+	{13, "UT_MOD_KNIFE_THROWN", "knife", MCWeapon, "Thrown knife"},
+	{14, "UT_MOD_BERETTA", "beretta", MCWeapon, "Beretta"},
+	{15, "UT_MOD_DEAGLE", "deagle", MCWeapon, "Desert Eagle"},
+	{16, "UT_MOD_SPAS", "spas", MCWeapon, "SPAS-12"},
+	{17, "UT_MOD_UMP45", "ump45", MCWeapon, "UMP45"},
+	{18, "UT_MOD_MP5K", "mp5k", MCWeapon, "MP5K"},
+	{19, "UT_MOD_LR300", "lr300", MCWeapon, "LR300"},
+	{20, "UT_MOD_G36", "g36", MCWeapon, "G36"},
+	{21, "UT_MOD_PSG1", "psg1", MCWeapon, "PSG-1"},
+	{22, "UT_MOD_HK69", "hk69", MCWeapon, "HK69"},
+	{23, "UT_MOD_BLED", "bled", MCOther, "Bled"},
+	{24, "UT_MOD_KICKED", "kick", MCWeapon, "Kicked"},
+	{25, "UT_MOD_HEGRENADE", "hegrenade", MCWeapon, "HE grenade"},
+	{28, "UT_MOD_SR8", "sr8", MCWeapon, "SR-8"},
+	{30, "UT_MOD_AK103", "ak103", MCWeapon, "AK-103"},
+	{31, "UT_MOD_SPLODED", "sploded", MCEnvironment, "Exploded"},
+	{32, "UT_MOD_SLAPPED", "slapped", MCOther, "Slapped"},
+	{33, "UT_MOD_SMITED", "smited", MCOther, "Smited"},
+	{34, "UT_MOD_BOMBED", "bombed", MCOther, "Bombed"},
+	{35, "UT_MOD_NUKED", "nuked", MCOther, "Nuked"},
+	{36, "UT_MOD_NEGEV", "negev", MCWeapon, "Negev"},
+	{37, "UT_MOD_HK69_HIT", "hk69", MCWeapon, "HK69 hit"},
+	{38, "UT_MOD_M4", "m4", MCWeapon, "M4"},
+	{39, "UT_MOD_GLOCK", "glock", MCWeapon, "Glock"},
+	{40, "UT_MOD_COLT1911", "colt1911", MCWeapon, "Colt 1911"},
+	{41, "UT_MOD_MAC11", "mac11", MCWeapon, "MAC-11"},
+	{42, "UT_MOD_FRF1", "frf1", MCWeapon, "FR-F1"},
+	{43, "UT_MOD_BENELLI", "benelli", MCWeapon, "Benelli"},
+	{44, "UT_MOD_P90", "p90", MCWeapon, "P90"},
+	{45, "UT_MOD_MAGNUM", "magnum", MCWeapon, "Magnum"},
+	{46, "UT_MOD_TOD50", "tod50", MCWeapon, "TOD-50"},
+	{47, "UT_MOD_FLAG", "flag", MCOther, "Flag"},
+	{48, "UT_MOD_GOOMBA", "goomba", MCWeapon, "Goomba stomp"},
+}
+
+// dialects are tried in order by DetectDialect, the last registered first.
+var dialects = []*Dialect{DialectUrbanTerror, DialectOpenArena, DialectCPMA, DialectOSP, DialectMissionpack, DialectBaseq3}
+
+// RegisterDialect adds a dialect to the detection, before the ones already
+// registered. It replaces the dialect of the same name. It must not be
+// called while logs are read.
+func RegisterDialect(d *Dialect) {
+	registered := []*Dialect{d}
+	for _, other := range dialects {
+		if other.Name != d.Name {
+			registered = append(registered, other)
+		}
+	}
+	dialects = registered
+}
+
+// LookupDialect returns the registered dialect of the given name.
+func LookupDialect(name string) (*Dialect, error) {
+	for _, d := range dialects {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown dialect: %s", name)
+}
+
+// DetectDialect returns the dialect of the server config of an InitGame,
+// DialectBaseq3 when none matches.
+func DetectDialect(serverConfig map[string]string) *Dialect {
+	for _, d := range dialects {
+		if d.Matches(serverConfig) {
+			return d
+		}
+	}
+	return DialectBaseq3
+}
+
+// Matches reports if the server config of an InitGame is of the dialect.
+func (d *Dialect) Matches(serverConfig map[string]string) bool {
+	gameName := serverConfig["gamename"]
+	for _, name := range d.GameNames {
+		if strings.EqualFold(name, gameName) {
+			return true
+		}
+	}
+	version := strings.ToLower(serverConfig["version"])
+	for _, v := range d.Versions {
+		if strings.Contains(version, strings.ToLower(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+// LogHeader returns the header of a log line on the dialect.
+func (d *Dialect) LogHeader(header string) LogHeader {
+	if h := GetLogHeader(header); h != LHUnknown {
+		return h
+	}
+	if h, ok := d.Headers[header]; ok {
+		return h
+	}
+	return LHUnknown
+}
+
+// MeansCatalog returns the means of death of the dialect, ordered by id.
+func (d *Dialect) MeansCatalog() []MeansOfDeath {
+	return append([]MeansOfDeath(nil), d.means()...)
+}
+
+func (d *Dialect) means() []MeansOfDeath {
+	if len(d.Means) == 0 {
+		return meansCatalog
+	}
+	return d.Means
+}
+
+// logsFlags reports if the dialect logs the flag actions on CTF lines, then
+// the flags touched on Item lines are not needed to infer them.
+func (d *Dialect) logsFlags() bool {
+	for _, h := range d.Headers {
+		if h == LHCTF {
+			return true
+		}
+	}
+	return false
+}
+
+// team reads the t userinfo key, a team number or a team code of the
+// dialect.
+func (d *Dialect) team(value string) (Team, error) {
+	if team, ok := d.Teams[value]; ok {
+		return team, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, &SyntaxError{LHClientUserinfoChanged, "expecting t to be an integer on userinfo"}
+	}
+	if n < int(TMFree) || n > int(TMSpectator) {
+		return 0, &SyntaxError{LHClientUserinfoChanged, fmt.Sprintf("unknown team on userinfo: %d", n)}
+	}
+	return Team(n), nil
+}
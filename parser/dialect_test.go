@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDetectDialect(t *testing.T) {
+	tests := map[string]struct {
+		serverConfig map[string]string
+		expected     *Dialect
+	}{
+		"Baseq3":           {serverConfig: map[string]string{"gamename": "baseq3", "version": "ioq3 1.36 linux-x86_64 Apr 12 2009"}, expected: DialectBaseq3},
+		"Empty":            {serverConfig: map[string]string{}, expected: DialectBaseq3},
+		"OpenArena":        {serverConfig: map[string]string{"gamename": "baseoa"}, expected: DialectOpenArena},
+		"OpenArenaVersion": {serverConfig: map[string]string{"gamename": "baseq3", "version": "ioq3+oa 1.35"}, expected: DialectOpenArena},
+		"CPMA":             {serverConfig: map[string]string{"gamename": "CPMA"}, expected: DialectCPMA},
+		"OSP":              {serverConfig: map[string]string{"gamename": "osp"}, expected: DialectOSP},
+		"UrbanTerror":      {serverConfig: map[string]string{"gamename": "q3urt42", "version": "ioq3 1.35 urt 4.2.023"}, expected: DialectUrbanTerror},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := DetectDialect(test.serverConfig)
+			if result != test.expected {
+				t.Errorf("Expected %v, but got %v", test.expected.Name, result.Name)
+			}
+		})
+	}
+}
+
+func TestDialectParseLine(t *testing.T) {
+	line := "1:02 Radio: 0 - 7 - 2 - \"Spawn\" - \"I'm going for the flag\""
+	event, err := DialectUrbanTerror.ParseLine(line)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := ModEvent{Name: "Radio:", Text: "0 - 7 - 2 - \"Spawn\" - \"I'm going for the flag\""}
+	if !reflect.DeepEqual(event.Data, expected) || event.HeaderType != LHMod {
+		t.Errorf("Expected %+v, but got %+v", expected, event)
+	}
+
+	var synErr *SyntaxError
+	if _, err := ParseLine(line); !errors.As(err, &synErr) || synErr.Header() != LHUnknown {
+		t.Errorf("Expected unknown header on baseq3, but got %v", err)
+	}
+
+	event, err = DialectUrbanTerror.ParseLine(`0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\BLUE\r\1`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if team := event.Data.(ClientUserinfoChanged).Userinfo.Team; team != TMBlue {
+		t.Errorf("Expected %v, but got %v", TMBlue, team)
+	}
+
+	event, err = DialectUrbanTerror.ParseLine("0:02 Hit: 1 0 4 8: Isgalamido hit Zeh in the Torso")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hit := Hit{VictimId: 1, AttackerId: 0, LocationId: 4, Location: "Torso", WeaponId: 8, Weapon: "lr300"}
+	if !reflect.DeepEqual(event.Data, hit) {
+		t.Errorf("Expected %+v, but got %+v", hit, event.Data)
+	}
+
+	event, err = DialectOpenArena.ParseLine("0:03 CTF: 2 2 1: Isgalamido captured the BLUE flag!")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctf := CTF{ClientId: 2, Flag: TMBlue, Action: FACapture}
+	if !reflect.DeepEqual(event.Data, ctf) {
+		t.Errorf("Expected %+v, but got %+v", ctf, event.Data)
+	}
+	// the kills of flag carriers are already on the kill lines
+	event, err = DialectOpenArena.ParseLine("0:04 CTF: 4 1 3: Mocinha fragged Isgalamido's flag carrier!")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := event.Data.(ModEvent); !ok {
+		t.Errorf("Expected a mod event, but got %+v", event.Data)
+	}
+}
+
+type modRecorder struct {
+	BaseHandler
+	mods []string
+}
+
+func (h *modRecorder) OnModEvent(game *Game, time string, mod ModEvent) {
+	h.mods = append(h.mods, mod.Name)
+}
+
+func TestGetGameDialect(t *testing.T) {
+	input := `  0:00 InitGame: \gamename\q3urt42\mapname\ut4_turnpike\g_gametype\4
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\1\r\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\2\r\0
+  0:02 Hit: 3 2 1 8: Isgalamido hit Zeh in the Head
+  0:02 Radio: 3 - 7 - 2 - "Spawn" - "Need backup"
+  0:03 Kill: 2 3 19: Isgalamido killed Zeh by UT_MOD_LR300
+  0:04 ShutdownGame:
+  0:05 InitGame: \gamename\baseq3\mapname\q3dm17
+  0:06 Hit: 3 2 1 8: Isgalamido hit Zeh in the Head
+  0:07 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	recorder := &modRecorder{}
+	gs.Register(recorder)
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	if game.Dialect != "urt" {
+		t.Errorf("Expected %v, but got %v", "urt", game.Dialect)
+	}
+	if game.KillCountByMeans["UT_MOD_LR300"] != 1 {
+		t.Errorf("Expected 1 LR300 kill, but got %v", game.KillCountByMeans)
+	}
+	if _, ok := game.KillCountByMeans["MOD_RAILGUN"]; ok {
+		t.Errorf("Expected only Urban Terror means, but got %v", game.KillCountByMeans)
+	}
+	if m, ok := LookupMeans("UT_MOD_LR300"); !ok || m.Family != "lr300" || m.Class != MCWeapon {
+		t.Errorf("Expected LR300 on the catalog, but got %+v", m)
+	}
+	if !reflect.DeepEqual(recorder.mods, []string{"Radio:"}) {
+		t.Errorf("Expected %v, but got %v", []string{"Radio:"}, recorder.mods)
+	}
+	expected := map[string]*WeaponAccuracy{"lr300": {Hits: 1, Headshots: 1}}
+	if weapons := game.PlayersInfoById[2].Weapons; !reflect.DeepEqual(weapons, expected) {
+		t.Errorf("Expected %v, but got %v", expected, weapons)
+	}
+	expected = map[string]*WeaponAccuracy{"lr300": {HitsReceived: 1}}
+	if weapons := game.PlayersInfoById[3].Weapons; !reflect.DeepEqual(weapons, expected) {
+		t.Errorf("Expected %v, but got %v", expected, weapons)
+	}
+
+	// the next game is baseq3 again, where Hit: is unknown
+	_, ok, err = gs.GetGame()
+	var synErr *SyntaxError
+	if ok || !errors.As(err, &synErr) {
+		t.Fatalf("Expected syntax error, got ok %v err %v", ok, err)
+	}
+}
+
+func TestGetGameForcedDialect(t *testing.T) {
+	input := `  0:00 InitGame: \gamename\baseq3\mapname\q3dm17
+  0:01 Award: 0 2: Isgalamido gained the EXCELLENT award!
+  0:02 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	gs.Dialect = DialectOpenArena
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil || game.Dialect != "openarena" {
+		t.Fatalf("Expected openarena game, got game %+v ok %v err %v", game, ok, err)
+	}
+}
+
+func TestRegisterDialect(t *testing.T) {
+	saved := dialects
+	defer func() { dialects = saved }()
+
+	custom := &Dialect{Name: "custom", GameNames: []string{"baseq3"}, Headers: map[string]LogHeader{"Radio:": LHMod}}
+	RegisterDialect(custom)
+	if d := DetectDialect(map[string]string{"gamename": "baseq3"}); d != custom {
+		t.Errorf("Expected %v, but got %v", custom.Name, d.Name)
+	}
+	if d, err := LookupDialect("custom"); err != nil || d != custom {
+		t.Errorf("Expected %v, but got %v %v", custom.Name, d, err)
+	}
+	if _, err := LookupDialect("other"); err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
@@ -11,6 +11,10 @@ func (e *SyntaxError) Error() string {
 	return fmt.Sprintf("[syntax error] %s: %s ", e.header.String(), e.message)
 }
 
+func (e *SyntaxError) Header() LogHeader {
+	return e.header
+}
+
 type ContextError struct {
 	header  LogHeader
 	message string
@@ -19,3 +23,7 @@ type ContextError struct {
 func (e *ContextError) Error() string {
 	return fmt.Sprintf("[context error] %s: %s ", e.header.String(), e.message)
 }
+
+func (e *ContextError) Header() LogHeader {
+	return e.header
+}
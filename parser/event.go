@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // TODO(pedro.silva) normalize data to lower case
@@ -24,44 +26,84 @@ var logHeaderByStrHeader = map[string]LogHeader{
 func GetLogHeader(header string) LogHeader {
 	if h, ok := logHeaderByStrHeader[header]; ok {
 		return h
+	} else if strings.HasPrefix(header, "red:") {
+		// the team scores have no space after the header: red:8  blue:6
+		return LHTeamScore
 	} else {
 		return LHUnknown
 	}
 }
 
-func getEvent(line string) (*Event[any], error) {
+// parseInfoString reads the backslash separated key value pairs used by the
+// server on InitGame lines.
+func parseInfoString(info string) map[string]string {
+	values := make(map[string]string)
+	pairs := strings.Split(strings.TrimPrefix(info, "\\"), "\\")
+	for i := 0; i+1 < len(pairs); i += 2 {
+		values[pairs[i]] = pairs[i+1]
+	}
+	return values
+}
+
+// ParseTime converts the MM:SS time of a log line to the elapsed duration.
+func ParseTime(t string) (time.Duration, error) {
+	minutes, seconds, found := strings.Cut(t, ":")
+	if !found {
+		return 0, fmt.Errorf("invalid time: %s", t)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 {
+		return 0, fmt.Errorf("invalid minutes on time: %s", t)
+	}
+	s, err := strconv.Atoi(seconds)
+	if err != nil || s < 0 || s > 59 {
+		return 0, fmt.Errorf("invalid seconds on time: %s", t)
+	}
+	return time.Duration(m)*time.Minute + time.Duration(s)*time.Second, nil
+}
+
+// ParseLine parses a log line of baseq3. Lines with syntax errors return an
+// empty event and a SyntaxError.
+func ParseLine(line string) (*Event, error) {
+	return DialectBaseq3.ParseLine(line)
+}
+
+// ParseLine parses a log line of the dialect. Lines with syntax errors return
+// an empty event and a SyntaxError.
+func (d *Dialect) ParseLine(line string) (*Event, error) {
 	words := strings.Fields(line)
 	if len(words) > 1 {
 		time := words[0]
 		logHeader := words[1]
 
-		switch GetLogHeader(logHeader) {
+		switch d.LogHeader(logHeader) {
 		case LHItem:
 			if len(words) != 4 {
-				return &Event[any]{}, &SyntaxError{LHItem, "expecting 4 words on log line"}
+				return &Event{}, &SyntaxError{LHItem, "expecting 4 words on log line"}
 			}
 
 			itemSplit := strings.Split(words[3], "_")
 			if len(itemSplit) < 2 {
-				return &Event[any]{}, &SyntaxError{LHItem, "expecting Item type to have at least 2 words"}
+				return &Event{}, &SyntaxError{LHItem, "expecting Item type to have at least 2 words"}
 			}
 
-			amount, err := strconv.Atoi(words[2])
+			clientId, err := strconv.Atoi(words[2])
 			if err != nil {
-				return &Event[any]{}, &SyntaxError{LHItem, "expecting Item amount to be an integer"}
+				return &Event{}, &SyntaxError{LHItem, "expecting clientId to be an integer"}
 			}
 
 			item := Item{
-				Amount:   amount,
-				Category: itemSplit[0],
-				Name:     itemSplit[1],
+				ClientId:  clientId,
+				Classname: words[3],
+				Category:  itemSplit[0],
+				Name:      itemSplit[1],
 			}
 
 			if len(itemSplit) > 2 {
 				item.SubType = itemSplit[2]
 			}
 
-			return &Event[any]{
+			return &Event{
 				HeaderType: LHItem,
 				Time:       time,
 				Data:       item,
@@ -69,9 +111,23 @@ func getEvent(line string) (*Event[any], error) {
 
 		case LHKill:
 			if len(words) < 5 {
-				return &Event[any]{}, &SyntaxError{LHKill, "expecting more than 5 words on log line"}
+				return &Event{}, &SyntaxError{LHKill, "expecting more than 5 words on log line"}
+			}
+			killerId, err := strconv.Atoi(words[2])
+			if err != nil {
+				return &Event{}, &SyntaxError{LHKill, "expecting killerId to be an integer"}
 			}
-			kill := Kill{}
+			victimId, err := strconv.Atoi(words[3])
+			if err != nil {
+				return &Event{}, &SyntaxError{LHKill, "expecting victimId to be an integer"}
+			}
+			// the means id is checked but the name is used, the ids depend
+			// on the game code, see Dialect.MeansCatalog
+			if _, err := strconv.Atoi(strings.TrimSuffix(words[4], ":")); err != nil {
+				return &Event{}, &SyntaxError{LHKill, "expecting meansId to be an integer"}
+			}
+
+			kill := Kill{KillerId: killerId, VictimId: victimId}
 			var buffer []string
 			for i := 5; i < len(words); i++ {
 				switch words[i] {
@@ -89,16 +145,16 @@ func getEvent(line string) (*Event[any], error) {
 			buffer = nil
 
 			if kill.Means == "" {
-				return &Event[any]{}, &SyntaxError{LHKill, "missing Means on log line"}
+				return &Event{}, &SyntaxError{LHKill, "missing Means on log line"}
 			}
 			if kill.Victim == "" {
-				return &Event[any]{}, &SyntaxError{LHKill, "missing Victim on log line"}
+				return &Event{}, &SyntaxError{LHKill, "missing Victim on log line"}
 			}
 			if kill.Killer == "" {
-				return &Event[any]{}, &SyntaxError{LHKill, "missing Killer on log line"}
+				return &Event{}, &SyntaxError{LHKill, "missing Killer on log line"}
 			}
 
-			return &Event[any]{
+			return &Event{
 				HeaderType: LHKill,
 				Time:       time,
 				Data:       kill,
@@ -106,34 +162,36 @@ func getEvent(line string) (*Event[any], error) {
 
 		case LHClientConnect:
 			if len(words) != 3 {
-				return &Event[any]{}, &SyntaxError{LHClientConnect, "expecting 3 words on on log line"}
+				return &Event{}, &SyntaxError{LHClientConnect, "expecting 3 words on on log line"}
 			}
 
 			clientId, err := strconv.Atoi(words[2])
 			if err != nil {
-				return &Event[any]{}, &SyntaxError{LHClientConnect, "expecting clientId to be an integer"}
+				return &Event{}, &SyntaxError{LHClientConnect, "expecting clientId to be an integer"}
 			}
 
 			clientConnect := ClientConnect{
 				ClientId: clientId,
 			}
 
-			return &Event[any]{
+			return &Event{
 				HeaderType: LHClientConnect,
 				Time:       time,
 				Data:       clientConnect,
 			}, nil
 
 		case LHInitGame:
-			// TODO(pedro.silva) join words from index 2 to last to parse server init data
-			return &Event[any]{
+			_, info, _ := strings.Cut(line, logHeader)
+			return &Event{
 				HeaderType: LHInitGame,
 				Time:       time,
-				Data:       InitGame{},
+				Data: InitGame{
+					ServerConfig: parseInfoString(strings.TrimSpace(info)),
+				},
 			}, nil
 
 		case LHExit:
-			return &Event[any]{
+			return &Event{
 				HeaderType: LHExit,
 				Time:       time,
 				Data: Exit{
@@ -141,8 +199,31 @@ func getEvent(line string) (*Event[any], error) {
 				},
 			}, nil
 
+		case LHTeamScore:
+			if len(words) != 3 || !strings.HasPrefix(words[2], "blue:") {
+				return &Event{}, &SyntaxError{LHTeamScore, "expecting red and blue scores on log line"}
+			}
+
+			red, err := strconv.Atoi(strings.TrimPrefix(words[1], "red:"))
+			if err != nil {
+				return &Event{}, &SyntaxError{LHTeamScore, "expecting red score to be an integer"}
+			}
+			blue, err := strconv.Atoi(strings.TrimPrefix(words[2], "blue:"))
+			if err != nil {
+				return &Event{}, &SyntaxError{LHTeamScore, "expecting blue score to be an integer"}
+			}
+
+			return &Event{
+				HeaderType: LHTeamScore,
+				Time:       time,
+				Data: TeamScore{
+					Red:  red,
+					Blue: blue,
+				},
+			}, nil
+
 		case LHShutdownGame:
-			return &Event[any]{
+			return &Event{
 				HeaderType: LHShutdownGame,
 				Time:       time,
 				Data:       ShutdownGame{},
@@ -150,22 +231,26 @@ func getEvent(line string) (*Event[any], error) {
 
 		case LHClientUserinfoChanged:
 			if len(words) < 4 {
-				return &Event[any]{}, &SyntaxError{LHClientUserinfoChanged, "expecting more than 4 words on log line"}
+				return &Event{}, &SyntaxError{LHClientUserinfoChanged, "expecting more than 4 words on log line"}
 			}
 
 			clientId, err := strconv.Atoi(words[2])
 			if err != nil {
-				return &Event[any]{}, &SyntaxError{LHClientUserinfoChanged, "expecting clientId to be an integer"}
+				return &Event{}, &SyntaxError{LHClientUserinfoChanged, "expecting clientId to be an integer"}
 			}
 
-			// (pedro.silva) refactor to parse function
-			username := strings.Split(strings.Join(words[3:], " "), "\\")[1]
+			userinfo, err := parseUserinfo(strings.Join(words[3:], " "), d)
+			if err != nil {
+				return &Event{}, err
+			}
 			clientUserinfoChanged := ClientUserinfoChanged{
 				ClientId: clientId,
-				Username: username,
+				Username: userinfo.Name,
+				Bot:      userinfo.Bot(),
+				Userinfo: userinfo,
 			}
 
-			return &Event[any]{
+			return &Event{
 				HeaderType: LHClientUserinfoChanged,
 				Time:       time,
 				Data:       clientUserinfoChanged,
@@ -173,19 +258,19 @@ func getEvent(line string) (*Event[any], error) {
 
 		case LHClientBegin:
 			if len(words) != 3 {
-				return &Event[any]{}, &SyntaxError{LHClientBegin, "expecting 3 words on log line"}
+				return &Event{}, &SyntaxError{LHClientBegin, "expecting 3 words on log line"}
 			}
 
 			clientId, err := strconv.Atoi(words[2])
 			if err != nil {
-				return &Event[any]{}, &SyntaxError{LHClientBegin, "expecting clientId to be an integer"}
+				return &Event{}, &SyntaxError{LHClientBegin, "expecting clientId to be an integer"}
 			}
 
 			clientBegin := ClientBegin{
 				ClientId: clientId,
 			}
 
-			return &Event[any]{
+			return &Event{
 				HeaderType: LHClientBegin,
 				Time:       time,
 				Data:       clientBegin,
@@ -193,49 +278,49 @@ func getEvent(line string) (*Event[any], error) {
 
 		case LHClientDisconnect:
 			if len(words) != 3 {
-				return &Event[any]{}, &SyntaxError{LHClientDisconnect, "expecting 3 words on log line"}
+				return &Event{}, &SyntaxError{LHClientDisconnect, "expecting 3 words on log line"}
 			}
 
 			clientId, err := strconv.Atoi(words[2])
 			if err != nil {
-				return &Event[any]{}, &SyntaxError{LHClientDisconnect, "expecting clientId to be an integer"}
+				return &Event{}, &SyntaxError{LHClientDisconnect, "expecting clientId to be an integer"}
 			}
 
 			clientDisconnect := ClientDisconnect{
 				ClientId: clientId,
 			}
 
-			return &Event[any]{
+			return &Event{
 				HeaderType: LHClientDisconnect,
 				Time:       time,
 				Data:       clientDisconnect,
 			}, nil
 
 		case LHLogDivision:
-			return &Event[any]{
+			return &Event{
 				HeaderType: LHLogDivision,
 				Time:       time,
-				Data:       nil,
+				Data:       LogDivision{},
 			}, nil
 
 		case LHScore:
 			if len(words) < 8 {
-				return &Event[any]{}, &SyntaxError{LHScore, "expecting 8 or more words on log line"}
+				return &Event{}, &SyntaxError{LHScore, "expecting 8 or more words on log line"}
 			}
 
 			score, err := strconv.Atoi(words[2])
 			if err != nil {
-				return &Event[any]{}, &SyntaxError{LHScore, "expecting points to be an integer"}
+				return &Event{}, &SyntaxError{LHScore, "expecting points to be an integer"}
 			}
 
 			ping, err := strconv.Atoi(words[4])
 			if err != nil {
-				return &Event[any]{}, &SyntaxError{LHScore, "expecting ping to be an integer"}
+				return &Event{}, &SyntaxError{LHScore, "expecting ping to be an integer"}
 			}
 
 			clientId, err := strconv.Atoi(words[6])
 			if err != nil {
-				return &Event[any]{}, &SyntaxError{LHScore, "expecting clientId to be an integer"}
+				return &Event{}, &SyntaxError{LHScore, "expecting clientId to be an integer"}
 			}
 
 			username := strings.Join(words[7:], " ")
@@ -247,25 +332,83 @@ func getEvent(line string) (*Event[any], error) {
 				Username: username,
 			}
 
-			return &Event[any]{
+			return &Event{
 				HeaderType: LHScore,
 				Time:       time,
 				Data:       s,
 			}, nil
 
 		case LHSay:
-			return &Event[any]{
+			_, text, _ := strings.Cut(line, logHeader)
+			say := Say{}
+			// usernames may have spaces, the message starts after the first colon
+			if username, message, found := strings.Cut(strings.TrimSpace(text), ": "); found {
+				say.Username = username
+				say.Message = message
+			} else {
+				say.Message = strings.TrimSpace(text)
+			}
+			return &Event{
 				HeaderType: LHSay,
 				Time:       time,
-				Data:       Say{},
+				Data:       say,
+			}, nil
+
+		case LHHit:
+			hit, err := d.parseHit(words)
+			if err != nil {
+				return &Event{}, err
+			}
+			return &Event{
+				HeaderType: LHHit,
+				Time:       time,
+				Data:       hit,
+			}, nil
+
+		case LHWeaponStats:
+			stats, err := parseWeaponStats(words)
+			if err != nil {
+				return &Event{}, err
+			}
+			return &Event{
+				HeaderType: LHWeaponStats,
+				Time:       time,
+				Data:       stats,
+			}, nil
+
+		case LHCTF:
+			ctf, known, err := parseCTF(words)
+			if err != nil {
+				return &Event{}, err
+			}
+			if known {
+				return &Event{
+					HeaderType: LHCTF,
+					Time:       time,
+					Data:       ctf,
+				}, nil
+			}
+			_, text, _ := strings.Cut(line, logHeader)
+			return &Event{
+				HeaderType: LHMod,
+				Time:       time,
+				Data:       ModEvent{Name: logHeader, Text: strings.TrimSpace(text)},
+			}, nil
+
+		case LHMod:
+			_, text, _ := strings.Cut(line, logHeader)
+			return &Event{
+				HeaderType: LHMod,
+				Time:       time,
+				Data:       ModEvent{Name: logHeader, Text: strings.TrimSpace(text)},
 			}, nil
 
 		case LHUnknown:
-			return &Event[any]{}, &SyntaxError{LHUnknown, "unknown log header"}
+			return &Event{}, &SyntaxError{LHUnknown, "unknown log header"}
 		}
 	}
 
-	return &Event[any]{}, &SyntaxError{LHUnknown, " header could not be found"}
+	return &Event{}, &SyntaxError{LHUnknown, " header could not be found"}
 }
 
 type LogHeader uint8
@@ -284,35 +427,60 @@ const (
 	LHLogDivision
 	LHScore
 	LHSay
+	LHTeamScore
+	// LHMod is a header of a Dialect without a parser on this package.
+	LHMod
+	LHHit
+	LHWeaponStats
+	LHCTF
 )
 
-type Event[T any] struct {
+// Event is a parsed log line. Data has the type of the header, e.g. Kill
+// for LHKill, so consumers can use a type switch on it.
+type Event struct {
 	HeaderType LogHeader
 	Time       string
-	Data       T
+	Data       EventData
+}
+
+// EventData is the content of an event. It is sealed, only the event types
+// of this package implement it.
+type EventData interface {
+	Header() LogHeader
+	eventData()
 }
 
 type Item struct {
-	Amount   int
-	Category string
-	Name     string
-	SubType  string
+	ClientId int
+	// Classname is the whole item name, such as weapon_rocketlauncher.
+	Classname string
+	Category  string
+	Name      string
+	SubType   string
 }
 
 type Kill struct {
-	Victim string
-	Killer string
-	Means  string
+	// KillerId is 1022 for <world> kills.
+	KillerId int
+	VictimId int
+	Victim   string
+	Killer   string
+	Means    string
 }
 
 type ClientConnect struct {
 	ClientId int
 }
 
-type InitGame struct{}
+type InitGame struct {
+	ServerConfig map[string]string
+}
 
 type ShutdownGame struct{}
 
+// LogDivision is the line of dashes the server logs between games.
+type LogDivision struct{}
+
 type ClientBegin struct {
 	ClientId int
 }
@@ -325,9 +493,18 @@ type Exit struct {
 	Reason string
 }
 
+// TeamScore is the final score of the teams, logged after Exit on team games.
+type TeamScore struct {
+	Red  int
+	Blue int
+}
+
 type ClientUserinfoChanged struct {
 	ClientId int
 	Username string
+	// Bot is true when the userinfo has the skill key, only set for bots.
+	Bot      bool
+	Userinfo Userinfo
 }
 
 type Score struct {
@@ -337,4 +514,73 @@ type Score struct {
 	Username string
 }
 
-type Say struct{}
+type Say struct {
+	Username string
+	Message  string
+}
+
+// Hit is a hit of a player on another, logged by Urban Terror.
+type Hit struct {
+	VictimId   int
+	AttackerId int
+	LocationId int
+	// Location is the body part hit, e.g. Head or Torso.
+	Location string
+	WeaponId int
+	// Weapon is the weapon family of WeaponId on the Dialect.
+	Weapon string
+}
+
+// WeaponStats are the statistics of a player logged at the end of the game by
+// OSP and CPMA. Weapons are keyed by weapon family.
+type WeaponStats struct {
+	ClientId       int
+	Weapons        map[string]WeaponAccuracy
+	DamageGiven    int
+	DamageReceived int
+}
+
+// ModEvent is a line of a header known by the Dialect of the log but not
+// parsed by this package, such as Radio: on Urban Terror.
+type ModEvent struct {
+	// Name is the header as logged, e.g. Radio:.
+	Name string
+	// Text is the rest of the line.
+	Text string
+}
+
+func (Item) Header() LogHeader                  { return LHItem }
+func (Kill) Header() LogHeader                  { return LHKill }
+func (ClientConnect) Header() LogHeader         { return LHClientConnect }
+func (InitGame) Header() LogHeader              { return LHInitGame }
+func (Exit) Header() LogHeader                  { return LHExit }
+func (TeamScore) Header() LogHeader             { return LHTeamScore }
+func (ShutdownGame) Header() LogHeader          { return LHShutdownGame }
+func (ClientUserinfoChanged) Header() LogHeader { return LHClientUserinfoChanged }
+func (ClientBegin) Header() LogHeader           { return LHClientBegin }
+func (ClientDisconnect) Header() LogHeader      { return LHClientDisconnect }
+func (LogDivision) Header() LogHeader           { return LHLogDivision }
+func (Score) Header() LogHeader                 { return LHScore }
+func (Say) Header() LogHeader                   { return LHSay }
+func (ModEvent) Header() LogHeader              { return LHMod }
+func (Hit) Header() LogHeader                   { return LHHit }
+func (WeaponStats) Header() LogHeader           { return LHWeaponStats }
+func (CTF) Header() LogHeader                   { return LHCTF }
+
+func (Item) eventData()                  {}
+func (Kill) eventData()                  {}
+func (ClientConnect) eventData()         {}
+func (InitGame) eventData()              {}
+func (Exit) eventData()                  {}
+func (TeamScore) eventData()             {}
+func (ShutdownGame) eventData()          {}
+func (ClientUserinfoChanged) eventData() {}
+func (ClientBegin) eventData()           {}
+func (ClientDisconnect) eventData()      {}
+func (LogDivision) eventData()           {}
+func (Score) eventData()                 {}
+func (Say) eventData()                   {}
+func (ModEvent) eventData()              {}
+func (Hit) eventData()                   {}
+func (WeaponStats) eventData()           {}
+func (CTF) eventData()                   {}
@@ -58,6 +58,10 @@ func TestGetLogHeader(t *testing.T) {
 			input:    "say:",
 			expected: LHSay,
 		},
+		"LHTeamScore": {
+			input:    "red:8",
+			expected: LHTeamScore,
+		},
 		"LHUnknown": {
 			input:    "UnknownHeader",
 			expected: LHUnknown,
@@ -77,74 +81,82 @@ func TestGetLogHeader(t *testing.T) {
 func TestGetEvent(t *testing.T) {
 	tests := map[string]struct {
 		input    string
-		expected *Event[any]
+		expected *Event
 		err      error
 	}{
 		"ValidItemEvent": {
 			input: "  20:42 Item: 2 item_armor_body",
-			expected: &Event[any]{
+			expected: &Event{
 				HeaderType: LHItem,
 				Time:       "20:42",
 				Data: Item{
-					Amount:   2,
-					Category: "item",
-					Name:     "armor",
-					SubType:  "body",
+					ClientId:  2,
+					Classname: "item_armor_body",
+					Category:  "item",
+					Name:      "armor",
+					SubType:   "body",
 				},
 			},
 			err: nil,
 		},
-		"InvalidItemEventAmount": {
+		"InvalidItemEventClientId": {
 			input:    "10: Item: aaa invalid_format",
-			expected: &Event[any]{},
-			err:      &SyntaxError{LHItem, "expecting Item amount to be an integer"},
+			expected: &Event{},
+			err:      &SyntaxError{LHItem, "expecting clientId to be an integer"},
 		},
 		"InvalidItemEventCount": {
 			input:    "10: Item: invalidFormat",
-			expected: &Event[any]{},
+			expected: &Event{},
 			err:      &SyntaxError{LHItem, "expecting 4 words on log line"},
 		},
 		"InvalidItemEventFormat": {
 			input:    "10: Item: 3 invalidFormat",
-			expected: &Event[any]{},
+			expected: &Event{},
 			err:      &SyntaxError{LHItem, "expecting Item type to have at least 2 words"},
 		},
 		"ValidKillEvent": {
 			input: " 20:54 Kill: 1022 2 22: killer killed victim by means",
-			expected: &Event[any]{
+			expected: &Event{
 				HeaderType: LHKill,
 				Time:       "20:54",
 				Data: Kill{
-					Killer: "killer",
-					Victim: "victim",
-					Means:  "means",
+					KillerId: 1022,
+					VictimId: 2,
+					Killer:   "killer",
+					Victim:   "victim",
+					Means:    "means",
 				},
 			},
 			err: nil,
 		},
 		"InvalidKillEventCount": {
 			input:    "15: Kill: invalid_format",
-			expected: &Event[any]{},
+			expected: &Event{},
 			err:      &SyntaxError{LHKill, "expecting more than 5 words on log line"},
 		},
+		"InvalidKillEventMeansId": {
+			input:    "20:54 Kill: 1022 2 x: killer killed victim by means",
+			expected: &Event{},
+			err:      &SyntaxError{LHKill, "expecting meansId to be an integer"},
+		},
 		"InvalidKillEventMissV": {
 			input:    "20:54 Kill: 1022 2 22: killer victim by means",
-			expected: &Event[any]{},
+			expected: &Event{},
 			err:      &SyntaxError{LHKill, "missing Victim on log line"},
 		},
 		"InvalidKillEventMissK": {
 			input:    "20:54 Kill: 1022 2 22: killer killed victim means",
-			expected: &Event[any]{},
+			expected: &Event{},
 			err:      &SyntaxError{LHKill, "missing Killer on log line"},
 		},
 		"InvalidKillEventMissM": {
 			input:    "20:54 Kill: 1022 2 22: killer killed victim by",
-			expected: &Event[any]{},
+			expected: &Event{},
 			err:      &SyntaxError{LHKill, "missing Means on log line"},
 		},
 		"ValidClientConnectEvent": {
 			input: " 20:34 ClientConnect: 2",
-			expected: &Event[any]{
+			expected: &Event{
 				HeaderType: LHClientConnect,
 				Time:       "20:34",
 				Data: ClientConnect{
@@ -155,17 +167,33 @@ func TestGetEvent(t *testing.T) {
 		},
 		"InvalidClientConnectEventId": {
 			input:    "20:34 ClientConnect: aa",
-			expected: &Event[any]{},
+			expected: &Event{},
 			err:      &SyntaxError{LHClientConnect, "expecting clientId to be an integer"},
 		},
 		"InvalidClientConnectEventCount": {
 			input:    "20:34 ClientConnect: aa aa aa",
-			expected: &Event[any]{},
+			expected: &Event{},
 			err:      &SyntaxError{LHClientConnect, "expecting 3 words on on log line"},
 		},
+		"ValidInitGameEvent": {
+			input: `  0:00 InitGame: \sv_hostname\Code Miner Server\g_gametype\0\g_redteam\\mapname\q3dm17`,
+			expected: &Event{
+				HeaderType: LHInitGame,
+				Time:       "0:00",
+				Data: InitGame{
+					ServerConfig: map[string]string{
+						"sv_hostname": "Code Miner Server",
+						"g_gametype":  "0",
+						"g_redteam":   "",
+						"mapname":     "q3dm17",
+					},
+				},
+			},
+			err: nil,
+		},
 		"ValidShutdownGameEvent": {
 			input: "25: ShutdownGame:",
-			expected: &Event[any]{
+			expected: &Event{
 				HeaderType: LHShutdownGame,
 				Time:       "25:",
 				Data:       ShutdownGame{},
@@ -174,7 +202,7 @@ func TestGetEvent(t *testing.T) {
 		},
 		"ValidClientBeginEvent": {
 			input: "30: ClientBegin: 2",
-			expected: &Event[any]{
+			expected: &Event{
 				HeaderType: LHClientBegin,
 				Time:       "30:",
 				Data: ClientBegin{
@@ -185,7 +213,7 @@ func TestGetEvent(t *testing.T) {
 		},
 		"ValidClientDisconnectEvent": {
 			input: "35: ClientDisconnect: 3",
-			expected: &Event[any]{
+			expected: &Event{
 				HeaderType: LHClientDisconnect,
 				Time:       "35:",
 				Data: ClientDisconnect{
@@ -196,16 +224,16 @@ func TestGetEvent(t *testing.T) {
 		},
 		"ValidLogDivisionEvent": {
 			input: "40: ------------------------------------------------------------",
-			expected: &Event[any]{
+			expected: &Event{
 				HeaderType: LHLogDivision,
 				Time:       "40:",
-				Data:       nil,
+				Data:       LogDivision{},
 			},
 			err: nil,
 		},
 		"ValidScoreEvent": {
 			input: " 11:57 score: 10 ping: 50 client: 4 username with spaces",
-			expected: &Event[any]{
+			expected: &Event{
 				HeaderType: LHScore,
 				Time:       "11:57",
 				Data: Score{
@@ -219,33 +247,119 @@ func TestGetEvent(t *testing.T) {
 		},
 		"InvalidScoreEvent": {
 			input:    " 11:57 score: invalid ping: 50 client: 4 username with spaces",
-			expected: &Event[any]{},
+			expected: &Event{},
 			err:      &SyntaxError{LHScore, "expecting score to be an integer"},
 		},
 		"ValidSayEvent": {
 			input: "11:57 say: asdasd",
-			expected: &Event[any]{
+			expected: &Event{
 				HeaderType: LHSay,
 				Time:       "11:57",
-				Data:       Say{},
+				Data:       Say{Message: "asdasd"},
+			},
+			err: nil,
+		},
+		"ValidSayEventWithUsername": {
+			input: "981:26 say: Dono da Bola: team blue: now",
+			expected: &Event{
+				HeaderType: LHSay,
+				Time:       "981:26",
+				Data: Say{
+					Username: "Dono da Bola",
+					Message:  "team blue: now",
+				},
+			},
+			err: nil,
+		},
+		"ValidClientUserinfoChangedEvent": {
+			input: `20:34 ClientUserinfoChanged: 2 n\Isgalamido\t\1\model\xian/default\hmodel\xian/default\g_redteam\\g_blueteam\\c1\4\c2\5\hc\70\w\0\l\0\tt\0\tl\0`,
+			expected: &Event{
+				HeaderType: LHClientUserinfoChanged,
+				Time:       "20:34",
+				Data: ClientUserinfoChanged{
+					ClientId: 2,
+					Username: "Isgalamido",
+					Userinfo: Userinfo{
+						Name:      "Isgalamido",
+						Team:      TMRed,
+						Model:     "xian/default",
+						HeadModel: "xian/default",
+						Handicap:  70,
+						Color1:    "4",
+						Color2:    "5",
+						Values: map[string]string{
+							"n": "Isgalamido", "t": "1", "model": "xian/default", "hmodel": "xian/default",
+							"g_redteam": "", "g_blueteam": "", "c1": "4", "c2": "5", "hc": "70",
+							"w": "0", "l": "0", "tt": "0", "tl": "0",
+						},
+					},
+				},
 			},
 			err: nil,
 		},
+		"ValidClientUserinfoChangedEventBot": {
+			input: `0:01 ClientUserinfoChanged: 3 n\Sarge Bot\t\0\skill\ 2.00`,
+			expected: &Event{
+				HeaderType: LHClientUserinfoChanged,
+				Time:       "0:01",
+				Data: ClientUserinfoChanged{
+					ClientId: 3,
+					Username: "Sarge Bot",
+					Bot:      true,
+					Userinfo: Userinfo{
+						Name:     "Sarge Bot",
+						Handicap: 100,
+						Skill:    2,
+						Values:   map[string]string{"n": "Sarge Bot", "t": "0", "skill": " 2.00"},
+					},
+				},
+			},
+			err: nil,
+		},
+		"InvalidClientUserinfoChangedEventNoName": {
+			input:    "0:01 ClientUserinfoChanged: 2 Isgalamido",
+			expected: &Event{},
+			err:      &SyntaxError{LHClientUserinfoChanged, "expecting n key on userinfo"},
+		},
+		"InvalidClientUserinfoChangedEventHandicap": {
+			input:    `0:01 ClientUserinfoChanged: 2 n\Isgalamido\hc\full`,
+			expected: &Event{},
+			err:      &SyntaxError{LHClientUserinfoChanged, "expecting hc to be an integer on userinfo"},
+		},
+		"InvalidClientUserinfoChangedEventTeam": {
+			input:    `0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\7`,
+			expected: &Event{},
+			err:      &SyntaxError{LHClientUserinfoChanged, "unknown team on userinfo: 7"},
+		},
+		"ValidTeamScoreEvent": {
+			input: "10:12 red:8  blue:6",
+			expected: &Event{
+				HeaderType: LHTeamScore,
+				Time:       "10:12",
+				Data:       TeamScore{Red: 8, Blue: 6},
+			},
+			err: nil,
+		},
+		"InvalidTeamScoreEvent": {
+			input:    "10:12 red:8  green:6",
+			expected: &Event{},
+			err:      &SyntaxError{LHTeamScore, "expecting red and blue scores on log line"},
+		},
 		"UnknownLogHeader": {
 			input:    "55: UnknownHeader: some data",
-			expected: &Event[any]{},
+			expected: &Event{},
 			err:      &SyntaxError{LHUnknown, "unknown log header"},
 		},
 		"HeaderNotFound": {
 			input:    "60: NonExistentHeader: some data",
-			expected: &Event[any]{},
+			expected: &Event{},
 			err:      &SyntaxError{LHUnknown, " header could not be found"},
 		},
 	}
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			result, err := getEvent(test.input)
+			result, err := ParseLine(test.input)
 			if err != nil && test.err == nil {
 				t.Errorf("Unexpected error: %v", err)
 			}
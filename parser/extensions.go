@@ -1,5 +1,10 @@
 package parser
 
+import (
+	"fmt"
+	"strings"
+)
+
 func (s LogHeader) String() string {
 	switch s {
 	case LHUnknown:
@@ -28,6 +33,172 @@ func (s LogHeader) String() string {
 		return "Score"
 	case LHSay:
 		return "Say"
+	case LHTeamScore:
+		return "TeamScore"
+	case LHMod:
+		return "Mod"
+	case LHHit:
+		return "Hit"
+	case LHWeaponStats:
+		return "WeaponStats"
+	case LHCTF:
+		return "CTF"
 	}
 	return "Unknown"
 }
+
+func (p ErrorPolicy) String() string {
+	switch p {
+	case EPLenient:
+		return "lenient"
+	case EPStrict:
+		return "strict"
+	case EPRecover:
+		return "recover"
+	}
+	return "lenient"
+}
+
+func ParseErrorPolicy(policy string) (ErrorPolicy, error) {
+	switch policy {
+	case "lenient", "":
+		return EPLenient, nil
+	case "strict":
+		return EPStrict, nil
+	case "recover":
+		return EPRecover, nil
+	}
+	return EPLenient, fmt.Errorf("unknown error policy: %s", policy)
+}
+
+func (c EndingCondition) String() string {
+	switch c {
+	case ECFraglimit:
+		return "fraglimit"
+	case ECTimelimit:
+		return "timelimit"
+	case ECCapturelimit:
+		return "capturelimit"
+	case ECVote:
+		return "vote"
+	case ECShutdown:
+		return "shutdown"
+	case ECAbandoned:
+		return "abandoned"
+	}
+	return "unknown"
+}
+
+func (c EndingCondition) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *EndingCondition) UnmarshalText(text []byte) error {
+	for _, candidate := range []EndingCondition{ECUnknown, ECFraglimit, ECTimelimit, ECCapturelimit, ECVote, ECShutdown, ECAbandoned} {
+		if candidate.String() == string(text) {
+			*c = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown ending condition: %s", text)
+}
+
+func (t GameTag) String() string {
+	switch t {
+	case GTWarmup:
+		return "warmup"
+	case GTFewHumans:
+		return "few-humans"
+	case GTNoKills:
+		return "no-kills"
+	case GTBotOnly:
+		return "bot-only"
+	case GTShort:
+		return "short"
+	}
+	return strings.Join(t.Names(), ",")
+}
+
+func (c MeansClass) String() string {
+	switch c {
+	case MCWeapon:
+		return "weapon"
+	case MCEnvironment:
+		return "environment"
+	case MCSelf:
+		return "self"
+	}
+	return "other"
+}
+
+func (c MeansClass) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *MeansClass) UnmarshalText(text []byte) error {
+	for _, candidate := range []MeansClass{MCWeapon, MCEnvironment, MCSelf, MCOther} {
+		if candidate.String() == string(text) {
+			*c = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown means class: %s", text)
+}
+
+func (t Team) String() string {
+	switch t {
+	case TMFree:
+		return "free"
+	case TMRed:
+		return "red"
+	case TMBlue:
+		return "blue"
+	case TMSpectator:
+		return "spectator"
+	}
+	return "unknown"
+}
+
+func (t Team) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *Team) UnmarshalText(text []byte) error {
+	for _, candidate := range []Team{TMFree, TMRed, TMBlue, TMSpectator} {
+		if candidate.String() == string(text) {
+			*t = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown team: %s", text)
+}
+
+func (a FlagAction) String() string {
+	switch a {
+	case FAPickup:
+		return "pickup"
+	case FADrop:
+		return "drop"
+	case FAReturn:
+		return "return"
+	case FACapture:
+		return "capture"
+	case FAAssist:
+		return "assist"
+	}
+	return "unknown"
+}
+
+func (a FlagAction) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *FlagAction) UnmarshalText(text []byte) error {
+	for _, candidate := range []FlagAction{FAPickup, FADrop, FAReturn, FACapture, FAAssist} {
+		if candidate.String() == string(text) {
+			*a = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown flag action: %s", text)
+}
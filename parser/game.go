@@ -2,195 +2,588 @@ package parser
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 
 	"github.com/rs/zerolog/log"
 )
 
+// DefaultMaxLineSize is the longest line read by default. InitGame and
+// userinfo lines can be much longer than the 64KB of a bufio.Scanner.
+const DefaultMaxLineSize = 1024 * 1024
+
+// ScannerOptions configure a GameScanner created by NewGameScanner. Zero
+// TagOptions, Scoring without a name and nil Awards take the values of
+// DefaultScannerOptions.
+type ScannerOptions struct {
+	ErrorPolicy ErrorPolicy
+	TagOptions  TagOptions
+	Scoring     ScoringRules
+	// Awards are the rules of the awards given at the end of each game.
+	Awards []AwardRule
+	// Dialect is the grammar of the log, nil to detect it on each InitGame.
+	Dialect *Dialect
+	// MaxLineSize is the longest line accepted, longer lines end the scan
+	// with bufio.ErrTooLong. Zero means DefaultMaxLineSize.
+	MaxLineSize int
+	// HoldPartialLine leaves a last line without a line break unread, for
+	// scans resumed later from State.
+	HoldPartialLine bool
+}
+
+func DefaultScannerOptions() ScannerOptions {
+	return ScannerOptions{
+		ErrorPolicy: EPLenient,
+		TagOptions:  DefaultTagOptions(),
+		Scoring:     DefaultScoringRules(),
+		Awards:      DefaultAwardRules(),
+		MaxLineSize: DefaultMaxLineSize,
+	}
+}
+
+// NewGameScanner creates a GameScanner that reads the log from r. The scan
+// stops with the error of ctx once it is done.
+func NewGameScanner(ctx context.Context, r io.Reader, opts ScannerOptions) *GameScanner {
+	maxLineSize := opts.MaxLineSize
+	if maxLineSize <= 0 {
+		maxLineSize = DefaultMaxLineSize
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+	gs := InitScanner(scanner)
+	gs.ctx = ctx
+	gs.ErrorPolicy = opts.ErrorPolicy
+	if opts.TagOptions != (TagOptions{}) {
+		gs.TagOptions = opts.TagOptions
+	}
+	if opts.Scoring.Name != "" {
+		gs.Scoring = opts.Scoring
+	}
+	if opts.Awards != nil {
+		gs.Awards = opts.Awards
+	}
+	gs.Dialect = opts.Dialect
+	gs.HoldPartialLine = opts.HoldPartialLine
+	return gs
+}
+
+// InitScanner creates a GameScanner that reads lines from scanner. The split
+// function of scanner is replaced so the read offset can be tracked.
 func InitScanner(scanner *bufio.Scanner) *GameScanner {
-	return &GameScanner{
+	gs := &GameScanner{
 		Scanner:            scanner,
+		ctx:                context.Background(),
 		buffer:             nil,
 		clientIdByUsername: make(map[string]int),
+		TagOptions:         DefaultTagOptions(),
+		Scoring:            DefaultScoringRules(),
+		Awards:             DefaultAwardRules(),
 	}
+	scanner.Split(gs.scanLines)
+	return gs
 }
 
-// TODO(pedro.silva) Add unit tests
+// GetGame reads the next game. ok is false once the input is over, then game
+// is the game left open by the end of the input, if any, and err is the read
+// error or the error of the context that stopped the scan, if any. Under
+// EPStrict the first error also ends the scan.
 func (gs *GameScanner) GetGame() (*Game, bool, error) {
-	var game *Game = nil
-	for event, ok := gs.scan(); ok; event, ok = gs.scan() {
-		switch event.HeaderType {
-		case LHKill:
-			if game == nil {
-				return nil, true, &ContextError{LHKill, "empty game"}
-			}
-			kill, ok := event.Data.(Kill)
-			if !ok {
-				return nil, true, &ContextError{LHKill, "bad parse of event"}
+	// continue the game left open by the end of the input or by Restore
+	game := gs.openGame
+	gs.openGame = nil
+	if game != nil {
+		reopenGame(game, gs.openWorld)
+	}
+	gs.openWorld = nil
+	for event, ok, err := gs.scan(); ok; event, ok, err = gs.scan() {
+		if err != nil {
+			if gs.ErrorPolicy == EPStrict {
+				return nil, false, err
 			}
-
-			var kInfo *PlayersInfo
-			if kId, ok := gs.clientIdByUsername[kill.Killer]; !ok {
-				return nil, true, &ContextError{LHKill, fmt.Sprintf("could not find killer id. username: %s", kill.Killer)}
-			} else {
-				if ki, ok := game.PlayersInfoById[kId]; ok {
-					kInfo = ki
-				} else {
-					return nil, true, &ContextError{LHKill, fmt.Sprintf("could not find killer information. id: %d", kId)}
-				}
-			}
-
-			game.KillCountByMeans[kill.Means]++
-			game.TotalKills++
-
-			if kill.Killer == kill.Victim {
-				kInfo.SuicideCount++
-				kInfo.DeathCount++
-				kInfo.DeathCountByWeapon[kill.Means]++
-			} else {
-				var vInfo *PlayersInfo
-				if vId, ok := gs.clientIdByUsername[kill.Victim]; ok {
-					if vi, ok := game.PlayersInfoById[vId]; ok {
-						vInfo = vi
-					} else {
-						return nil, true, &ContextError{LHKill, fmt.Sprintf("could not find victim information. id: %d", vId)}
-					}
-				} else {
-					return nil, true, &ContextError{LHKill, fmt.Sprintf("could not find victim id. username: %s", kill.Victim)}
-				}
-
-				vInfo.DeathCount++
-				vInfo.DeathCountBySource[kill.Killer]++
-				vInfo.DeathCountByWeapon[kill.Means]++
-				if kInfo.Id == 0 { // world kill
-					vInfo.Score--
-				}
-
-				kInfo.KillCount++
-				kInfo.Score++
-				kInfo.KillCountByPlayerTag[kill.Victim]++
-				kInfo.KillCountByMean[kill.Means]++
+			log.Warn().Msg(fmt.Sprintf("scan could not parse line correctly. error: %s", err))
+			if gs.ErrorPolicy == EPRecover {
+				gs.addAnomaly(game, event.Time, err)
 			}
+			continue
+		}
 
-		case LHInitGame:
+		switch data := event.Data.(type) {
+		case InitGame:
 			if game != nil {
 				// put init event back to buffer
 				gs.unScan(event)
 				game.EndingReason = "SERVER_UNEXPECTED_SHUTDOWN"
-				endGame(game)
+				gs.endGame(game, true)
 				return game, true, nil
 			}
+			dialect := gs.detectDialect(data.ServerConfig)
 			game = &Game{
 				PlayersInfoById: map[int]*PlayersInfo{
 					0: initPlayerInfo(0),
 				},
-				KillCountByMeans: initKillCountByMeans(),
+				KillCountByMeans: initKillCountByMeans(dialect),
+				Anomalies:        gs.pendingAnomalies,
+				ServerConfig:     data.ServerConfig,
+				StartTime:        event.Time,
+				EndTime:          event.Time,
+				ScoringRules:     gs.Scoring.Name,
+				Dialect:          dialect.Name,
 			}
+			gs.pendingAnomalies = nil
 			gs.clientIdByUsername["<world>"] = 0
 			game.PlayersInfoById[0].Username = "<world>"
-		case LHShutdownGame:
-			if game == nil {
-				return nil, true, &ContextError{LHShutdownGame, "empty game"}
-			}
-			if game.EndingReason == "" {
-				game.EndingReason = "SERVER_UNEXPECTED_SHUTDOWN"
+			gs.dispatch(game, event)
+			continue
+		case ShutdownGame:
+			if game != nil {
+				game.EndTime = event.Time
+				game.Shutdown = true
+				if game.EndingReason == "" {
+					game.EndingReason = "SERVER_UNEXPECTED_SHUTDOWN"
+				}
+				gs.endGame(game, true)
+				return game, true, nil
 			}
-			endGame(game)
-			return game, true, nil
-		case LHExit:
-			if game == nil {
-				return nil, true, &ContextError{LHExit, "empty game"}
+			err = &ContextError{LHShutdownGame, "empty game"}
+		default:
+			err = gs.handleEvent(game, event)
+			if game != nil {
+				game.EndTime = event.Time
+				if err == nil {
+					gs.dispatch(game, event)
+				}
 			}
-			exit, ok := event.Data.(Exit)
-			if !ok {
-				return nil, true, &ContextError{LHExit, "bad parse of event"}
+		}
+
+		if err != nil {
+			switch gs.ErrorPolicy {
+			case EPStrict:
+				return nil, false, err
+			case EPRecover:
+				gs.addAnomaly(game, event.Time, err)
+			default:
+				return nil, true, err
 			}
-			game.EndingReason = exit.Reason
-		case LHClientConnect:
-			if game == nil {
-				return nil, true, &ContextError{LHClientConnect, "empty game"}
+		}
+	}
+
+	// EOF, the <world> is kept to reopen the game
+	gs.openGame = game
+	if game != nil {
+		gs.openWorld = game.PlayersInfoById[0]
+	}
+	gs.endGame(game, false)
+	return game, false, gs.readErr
+}
+
+// ScanResult is a game sent by Games, or an error of the scan.
+type ScanResult struct {
+	Game *Game
+	Err  error
+}
+
+// Games reads the games on a new goroutine and sends them on the returned
+// channel, closed at the end of the input. The game left open by the end of
+// the input is sent as well, followed by the read error if any. Errors that
+// drop a game under EPLenient are sent without a game. The goroutine stops
+// when the context of the scanner is done, the results not received by then,
+// including the error of the context, are not sent.
+func (gs *GameScanner) Games() <-chan ScanResult {
+	results := make(chan ScanResult)
+	go func() {
+		defer close(results)
+		send := func(result ScanResult) bool {
+			select {
+			case results <- result:
+				return true
+			case <-gs.ctx.Done():
+				return false
 			}
-			cc, ok := event.Data.(ClientConnect)
-			if !ok {
-				return nil, true, &ContextError{LHClientConnect, "bad parse of event"}
+		}
+		for {
+			game, ok, err := gs.GetGame()
+			if ok {
+				if !send(ScanResult{Game: game, Err: err}) {
+					return
+				}
+				continue
 			}
-			game.PlayersInfoById[cc.ClientId] = initPlayerInfo(cc.ClientId)
-		case LHClientUserinfoChanged:
-			if game == nil {
-				return nil, true, &ContextError{LHClientUserinfoChanged, "empty game"}
+			if game != nil && !send(ScanResult{Game: game}) {
+				return
 			}
-			cuic, ok := event.Data.(ClientUserinfoChanged)
-			if !ok {
-				return nil, true, &ContextError{LHClientUserinfoChanged, "bad parse of event"}
+			if err != nil {
+				send(ScanResult{Err: err})
 			}
+			return
+		}
+	}()
+	return results
+}
 
-			if pi, ok := game.PlayersInfoById[cuic.ClientId]; ok {
-				pi.Username = cuic.Username
-				gs.clientIdByUsername[cuic.Username] = cuic.ClientId
-			} else {
-				return nil, true, &ContextError{LHClientUserinfoChanged, fmt.Sprintf("inexistent client change information. id: %d", cuic.ClientId)}
+// State returns what is needed to resume the scan from the last line applied
+// to a game. It should be called between calls to GetGame.
+func (gs *GameScanner) State() ScannerState {
+	offset := gs.offset
+	if gs.buffer != nil {
+		// the buffered line will be read again after a resume
+		offset = gs.lineStart
+	}
+	clientIdByUsername := make(map[string]int, len(gs.clientIdByUsername))
+	for username, id := range gs.clientIdByUsername {
+		clientIdByUsername[username] = id
+	}
+	return ScannerState{
+		Offset:             offset,
+		ClientIdByUsername: clientIdByUsername,
+		Game:               gs.openGame,
+		World:              gs.openWorld,
+		PendingAnomalies:   gs.pendingAnomalies,
+	}
+}
+
+// Restore sets the state saved by State. The Scanner must read the input
+// from state.Offset.
+func (gs *GameScanner) Restore(state ScannerState) {
+	gs.offset = state.Offset
+	gs.lineStart = state.Offset
+	gs.buffer = nil
+	gs.clientIdByUsername = make(map[string]int, len(state.ClientIdByUsername))
+	for username, id := range state.ClientIdByUsername {
+		gs.clientIdByUsername[username] = id
+	}
+	gs.openGame = state.Game
+	gs.openWorld = state.World
+	gs.pendingAnomalies = state.PendingAnomalies
+	gs.dialect = nil
+	if state.Game != nil {
+		if d, err := LookupDialect(state.Game.Dialect); err == nil {
+			gs.dialect = d
+		}
+	}
+}
+
+// detectDialect sets the dialect of the game started by an InitGame.
+func (gs *GameScanner) detectDialect(serverConfig map[string]string) *Dialect {
+	gs.dialect = gs.Dialect
+	if gs.dialect == nil {
+		gs.dialect = DetectDialect(serverConfig)
+	}
+	return gs.dialect
+}
+
+// currentDialect is the dialect used to parse the next line.
+func (gs *GameScanner) currentDialect() *Dialect {
+	if gs.dialect != nil {
+		return gs.dialect
+	}
+	if gs.Dialect != nil {
+		return gs.Dialect
+	}
+	return DialectBaseq3
+}
+
+// scanLines is bufio.ScanLines keeping track of the bytes read. Under
+// HoldPartialLine the unterminated tail of the input is left out of the
+// offset and unread, as it may still be being written, so a resumed scan
+// reads it whole.
+func (gs *GameScanner) scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && gs.HoldPartialLine && bytes.IndexByte(data, '\n') < 0 {
+		return 0, nil, nil
+	}
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if token != nil {
+		gs.lineStart = gs.offset
+		gs.offset += int64(advance)
+	}
+	return advance, token, err
+}
+
+// handleEvent applies an event to the game being built. Lookups that cannot
+// be resolved are attributed to the unknown player under EPRecover instead of
+// failing the event.
+func (gs *GameScanner) handleEvent(game *Game, event *Event) error {
+	gs.flagEvents = nil
+	switch data := event.Data.(type) {
+	case Kill:
+		if game == nil {
+			return &ContextError{LHKill, "empty game"}
+		}
+		kill := data
+
+		kInfo, err := gs.findPlayer(game, kill.Killer, "killer")
+		if err != nil {
+			if gs.ErrorPolicy != EPRecover {
+				return err
 			}
-		case LHClientDisconnect:
-			if game == nil {
-				return nil, true, &ContextError{LHClientDisconnect, "empty game"}
+			gs.addAnomaly(game, event.Time, err)
+			kInfo = game.unknownPlayer()
+		}
+
+		game.KillCountByMeans[kill.Means]++
+		game.TotalKills++
+		record := KillRecord{
+			Time:     event.Time,
+			KillerId: kInfo.Id,
+			Killer:   kill.Killer,
+			Victim:   kill.Victim,
+			Means:    kill.Means,
+		}
+
+		if kill.Killer == kill.Victim {
+			kInfo.SuicideCount++
+			kInfo.DeathCount++
+			kInfo.DeathCountByWeapon[kill.Means]++
+			kInfo.Score += gs.Scoring.Suicide
+			kInfo.KillStreak = 0
+			gs.dropFlag(game, event.Time, kInfo, kInfo)
+		} else {
+			vInfo, err := gs.findPlayer(game, kill.Victim, "victim")
+			if err != nil {
+				if gs.ErrorPolicy != EPRecover {
+					return err
+				}
+				gs.addAnomaly(game, event.Time, err)
+				vInfo = game.unknownPlayer()
 			}
 
-			cd, ok := event.Data.(ClientDisconnect)
-			if !ok {
-				return nil, true, &ContextError{LHClientDisconnect, "bad parse of event"}
+			vInfo.DeathCount++
+			vInfo.DeathCountBySource[kill.Killer]++
+			vInfo.DeathCountByWeapon[kill.Means]++
+			if kInfo.Id == 0 { // world kill
+				vInfo.Score += gs.Scoring.WorldDeath
 			}
+			vInfo.KillStreak = 0
 
-			if pi, ok := game.PlayersInfoById[cd.ClientId]; ok {
-				game.DisconnectedPlayers = append(game.DisconnectedPlayers, pi)
-				delete(game.PlayersInfoById, cd.ClientId)
-				delete(gs.clientIdByUsername, pi.Username)
+			if isTeamKill(kInfo, vInfo) {
+				record.TeamKill = true
+				game.TotalTeamKills++
+				kInfo.TeamKillCount++
+				kInfo.Score += gs.Scoring.TeamKill
+				vInfo.TeamDeathCountBySource[kill.Killer]++
 			} else {
-				return nil, true, &ContextError{LHClientUserinfoChanged, fmt.Sprintf("inexistent client disconnected. id: %d", cd.ClientId)}
+				kInfo.KillCount++
+				kInfo.KillCountByPlayerTag[kill.Victim]++
+				kInfo.KillCountByMean[kill.Means]++
+				kInfo.KillStreak++
+				kInfo.BestKillStreak = max(kInfo.BestKillStreak, kInfo.KillStreak)
+				kInfo.Score += gs.Scoring.killPoints(kill.Means, kInfo.KillStreak)
 			}
-		case LHScore:
-		case LHClientBegin:
-		case LHItem:
-		case LHLogDivision:
-		case LHSay:
+			gs.dropFlag(game, event.Time, kInfo, vInfo)
+		}
+		game.Kills = append(game.Kills, record)
+
+	case Exit:
+		if game == nil {
+			return &ContextError{LHExit, "empty game"}
+		}
+		exit := data
+		game.EndingReason = exit.Reason
+	case TeamScore:
+		if game == nil {
+			return &ContextError{LHTeamScore, "empty game"}
 		}
+		teamScore := data
+		game.TeamScore = &teamScore
+	case ClientConnect:
+		if game == nil {
+			return &ContextError{LHClientConnect, "empty game"}
+		}
+		cc := data
+		game.PlayersInfoById[cc.ClientId] = initPlayerInfo(cc.ClientId)
+	case ClientUserinfoChanged:
+		if game == nil {
+			return &ContextError{LHClientUserinfoChanged, "empty game"}
+		}
+		cuic := data
+
+		pi, ok := game.PlayersInfoById[cuic.ClientId]
+		if !ok {
+			err := &ContextError{LHClientUserinfoChanged, fmt.Sprintf("inexistent client change information. id: %d", cuic.ClientId)}
+			if gs.ErrorPolicy != EPRecover {
+				return err
+			}
+			// the connect line was lost, keep the player anyway
+			gs.addAnomaly(game, event.Time, err)
+			pi = initPlayerInfo(cuic.ClientId)
+			game.PlayersInfoById[cuic.ClientId] = pi
+		}
+		pi.Username = cuic.Username
+		pi.Bot = cuic.Bot
+		pi.setUserinfo(event.Time, cuic.Userinfo)
+		gs.clientIdByUsername[cuic.Username] = cuic.ClientId
+	case ClientDisconnect:
+		if game == nil {
+			return &ContextError{LHClientDisconnect, "empty game"}
+		}
+
+		cd := data
+
+		if pi, ok := game.PlayersInfoById[cd.ClientId]; ok {
+			gs.dropFlag(game, event.Time, nil, pi)
+			game.DisconnectedPlayers = append(game.DisconnectedPlayers, pi)
+			delete(game.PlayersInfoById, cd.ClientId)
+			delete(gs.clientIdByUsername, pi.Username)
+		} else {
+			return &ContextError{LHClientUserinfoChanged, fmt.Sprintf("inexistent client disconnected. id: %d", cd.ClientId)}
+		}
+	case Item:
+		item := data
+		if game != nil {
+			record := ItemRecord{Time: event.Time, ClientId: item.ClientId, Item: item.Classname}
+			if pi, ok := game.PlayersInfoById[item.ClientId]; ok {
+				record.Username = pi.Username
+				// the dialects with CTF lines log the flag actions there
+				if flag, ok := flagOf(item.Classname); ok && !gs.currentDialect().logsFlags() {
+					gs.touchFlag(game, event.Time, pi, flag)
+				}
+			}
+			game.Items = append(game.Items, record)
+		}
+	case Say:
+		say := data
+		if game != nil {
+			game.Chat = append(game.Chat, ChatRecord{Time: event.Time, Username: say.Username, Message: say.Message})
+		}
+	case Score:
+		// score lines are a scoreboard dump, the players that already left
+		// are not on it
+		if game != nil {
+			if pi, ok := game.PlayersInfoById[data.ClientId]; ok {
+				pi.Pings = append(pi.Pings, data.Ping)
+			}
+		}
+	case ClientBegin:
+	case LogDivision:
+	case Hit:
+		if game == nil {
+			return &ContextError{LHHit, "empty game"}
+		}
+		// as on the score lines, the hits of clients that are not on the
+		// game, such as the ones that just left, are skipped
+		if attacker, ok := game.PlayersInfoById[data.AttackerId]; ok {
+			a := attacker.weapon(data.Weapon)
+			a.Hits++
+			if isHeadshot(data.Location) {
+				a.Headshots++
+			}
+		}
+		if victim, ok := game.PlayersInfoById[data.VictimId]; ok {
+			victim.weapon(data.Weapon).HitsReceived++
+		}
+	case WeaponStats:
+		if game == nil {
+			return &ContextError{LHWeaponStats, "empty game"}
+		}
+		pi, ok := game.PlayersInfoById[data.ClientId]
+		if !ok {
+			break
+		}
+		// the stats are totals of the game, a later line replaces them
+		for family, stats := range data.Weapons {
+			a := pi.weapon(family)
+			a.Shots, a.Hits, a.Kills, a.Deaths = stats.Shots, stats.Hits, stats.Kills, stats.Deaths
+		}
+		pi.DamageGiven = data.DamageGiven
+		pi.DamageReceived = data.DamageReceived
+	case CTF:
+		if game == nil {
+			return &ContextError{LHCTF, "empty game"}
+		}
+		if pi, ok := game.PlayersInfoById[data.ClientId]; ok {
+			gs.applyCTF(game, event.Time, pi, data)
+		}
+	case ModEvent:
+	}
+	return nil
+}
+
+// isTeamKill reports if the killer and the victim are on the same team. On
+// free for all games every player is on TMFree, so there are no team kills.
+func isTeamKill(killer *PlayersInfo, victim *PlayersInfo) bool {
+	if killer.Id == 0 || killer.Id == UnknownPlayerId || victim.Id == UnknownPlayerId {
+		return false
 	}
+	return killer.Team == victim.Team && (killer.Team == TMRed || killer.Team == TMBlue)
+}
 
-	// EOF
-	endGame(game)
-	return game, false, nil
+// findPlayer resolves a username from a kill line to the player information
+// of the current game. role is only used to describe the failure.
+func (gs *GameScanner) findPlayer(game *Game, username string, role string) (*PlayersInfo, error) {
+	id, ok := gs.clientIdByUsername[username]
+	if !ok {
+		return nil, &ContextError{LHKill, fmt.Sprintf("could not find %s id. username: %s", role, username)}
+	}
+	info, ok := game.PlayersInfoById[id]
+	if !ok {
+		return nil, &ContextError{LHKill, fmt.Sprintf("could not find %s information. id: %d", role, id)}
+	}
+	return info, nil
+}
+
+// addAnomaly records a problem found while building a game. Problems found
+// outside of a game are kept until the next game starts.
+func (gs *GameScanner) addAnomaly(game *Game, time string, err error) {
+	anomaly := Anomaly{
+		Time:    time,
+		Message: err.Error(),
+		Err:     err,
+	}
+	if game == nil {
+		gs.pendingAnomalies = append(gs.pendingAnomalies, anomaly)
+		return
+	}
+	game.Anomalies = append(game.Anomalies, anomaly)
 }
 
-func (gs *GameScanner) scan() (*Event[any], bool) {
+func (gs *GameScanner) scan() (*Event, bool, error) {
 
 	if gs.buffer == nil {
+		if gs.readErr != nil {
+			return nil, false, nil
+		}
+		if err := gs.ctx.Err(); err != nil {
+			gs.readErr = err
+			return nil, false, nil
+		}
 		if gs.Scanner.Scan() {
 			line := strings.TrimSpace(gs.Scanner.Text())
-			event, err := getEvent(line)
+			event, err := gs.currentDialect().ParseLine(line)
+			if gs.onLine != nil {
+				gs.onLine(line, event, err)
+			}
 			if err != nil {
-				log.Warn().Msg(fmt.Sprintf("scan could not parse line correctly. error: %s | line: %s", err, line))
-				return gs.scan()
+				if words := strings.Fields(line); len(words) > 0 {
+					event.Time = words[0]
+				}
+				return event, true, fmt.Errorf("%w| line: %s", err, line)
 			}
-			// Check for errors during scan
+			return event, true, nil
+		} else {
 			if err := gs.Scanner.Err(); err != nil {
-				log.Warn().Msg(fmt.Sprintf("error reading file. error: %s", err))
+				gs.readErr = fmt.Errorf("error reading log: %w", err)
 			}
-			return event, true
-		} else {
-			return nil, false
+			return nil, false, nil
 		}
 	} else {
 		ret := gs.buffer
 		gs.buffer = nil
-		return ret, true
+		return ret, true, nil
 	}
 }
 
-func (gs *GameScanner) unScan(event *Event[any]) {
+func (gs *GameScanner) unScan(event *Event) {
 	gs.buffer = event
 }
 
-func endGame(game *Game) {
+// endGame moves the <world> out of the players and tags the game. final is
+// false for the game left open by the end of the input.
+func (gs *GameScanner) endGame(game *Game, final bool) {
 	if game != nil {
 		world := game.PlayersInfoById[0]
 		game.WorldKillStatus = WorldKillStatus{
@@ -199,54 +592,119 @@ func endGame(game *Game) {
 			KillCountByPlayerTag: world.KillCountByPlayerTag,
 		}
 		delete(game.PlayersInfoById, 0)
+		game.closeCarries()
+		game.Tags = game.Classify(gs.TagOptions)
+		game.Awards = GiveAwards(gs.Awards, AwardScopeGame, game.AwardStats())
+		for _, h := range gs.handlers {
+			if final {
+				h.OnGameEnd(game)
+			} else {
+				h.OnInputEnd(game)
+			}
+		}
 	}
 }
 
+// Hash identifies the game by its content: server config, times, ending,
+// players and kill timeline. Statistics derived from them do not change it.
+func (game *Game) Hash() string {
+	h := sha256.New()
+
+	keys := make([]string, 0, len(game.ServerConfig))
+	for k := range game.ServerConfig {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, game.ServerConfig[k])
+	}
+	fmt.Fprintf(h, "%s %s %s\n", game.StartTime, game.EndTime, game.EndingReason)
+
+	var players []string
+	for _, pi := range game.PlayersInfoById {
+		players = append(players, pi.Username)
+	}
+	for _, pi := range game.DisconnectedPlayers {
+		players = append(players, pi.Username)
+	}
+	sort.Strings(players)
+	fmt.Fprintln(h, strings.Join(players, "\\"))
+
+	for _, k := range game.Kills {
+		fmt.Fprintf(h, "%s %s %s %s\n", k.Time, k.Killer, k.Victim, k.Means)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// unknownPlayer returns the player that receives the events whose players
+// could not be resolved, creating it on first use.
+func (game *Game) unknownPlayer() *PlayersInfo {
+	if pi, ok := game.PlayersInfoById[UnknownPlayerId]; ok {
+		return pi
+	}
+	pi := initPlayerInfo(UnknownPlayerId)
+	pi.Username = UnknownPlayerName
+	game.PlayersInfoById[UnknownPlayerId] = pi
+	return pi
+}
+
+// reopenGame undoes endGame so a game can receive more events. world is the
+// <world> removed by endGame, it is rebuilt from the WorldKillStatus when
+// missing.
+func reopenGame(game *Game, world *PlayersInfo) {
+	if _, ok := game.PlayersInfoById[0]; ok {
+		return
+	}
+	if world == nil {
+		world = initPlayerInfo(0)
+		world.Username = "<world>"
+		world.KillCount = game.WorldKillStatus.KillCount
+		if game.WorldKillStatus.KillCountByMeans != nil {
+			world.KillCountByMean = game.WorldKillStatus.KillCountByMeans
+		}
+		if game.WorldKillStatus.KillCountByPlayerTag != nil {
+			world.KillCountByPlayerTag = game.WorldKillStatus.KillCountByPlayerTag
+		}
+	}
+	game.PlayersInfoById[0] = world
+	game.WorldKillStatus = WorldKillStatus{}
+	game.Tags = 0
+	game.Awards = nil
+}
+
 func initPlayerInfo(id int) *PlayersInfo {
 	return &PlayersInfo{
-		Id:                   id,
-		KillCount:            0,
-		DeathCount:           0,
-		SuicideCount:         0,
-		DeathCountByWeapon:   make(map[string]int),
-		DeathCountBySource:   make(map[string]int),
-		KillCountByMean:      make(map[string]int),
-		KillCountByPlayerTag: make(map[string]int),
-	}
-}
-
-func initKillCountByMeans() map[string]int {
-	return map[string]int{
-		"MOD_UNKNOWN":        0,
-		"MOD_SHOTGUN":        0,
-		"MOD_GAUNTLET":       0,
-		"MOD_MACHINEGUN":     0,
-		"MOD_GRENADE":        0,
-		"MOD_GRENADE_SPLASH": 0,
-		"MOD_ROCKET":         0,
-		"MOD_ROCKET_SPLASH":  0,
-		"MOD_PLASMA":         0,
-		"MOD_PLASMA_SPLASH":  0,
-		"MOD_RAILGUN":        0,
-		"MOD_LIGHTNING":      0,
-		"MOD_BFG":            0,
-		"MOD_BFG_SPLASH":     0,
-		"MOD_WATER":          0,
-		"MOD_SLIME":          0,
-		"MOD_LAVA":           0,
-		"MOD_CRUSH":          0,
-		"MOD_TELEFRAG":       0,
-		"MOD_FALLING":        0,
-		"MOD_SUICIDE":        0,
-		"MOD_TARGET_LASER":   0,
-		"MOD_TRIGGER_HURT":   0,
-		"MOD_NAIL":           0,
-		"MOD_CHAINGUN":       0,
-		"MOD_PROXIMITY_MINE": 0,
-		"MOD_KAMIKAZE":       0,
-		"MOD_JUICED":         0,
-		"MOD_GRAPPLE":        0,
+		Id:                     id,
+		KillCount:              0,
+		DeathCount:             0,
+		SuicideCount:           0,
+		DeathCountByWeapon:     make(map[string]int),
+		DeathCountBySource:     make(map[string]int),
+		TeamDeathCountBySource: make(map[string]int),
+		KillCountByMean:        make(map[string]int),
+		KillCountByPlayerTag:   make(map[string]int),
+	}
+}
+
+// setUserinfo keeps the team and handicap of a userinfo, recording them on
+// the history when they change.
+func (pi *PlayersInfo) setUserinfo(time string, userinfo Userinfo) {
+	if len(pi.TeamChanges) == 0 || pi.Team != userinfo.Team {
+		pi.TeamChanges = append(pi.TeamChanges, TeamChange{Time: time, Team: userinfo.Team})
+	}
+	if len(pi.HandicapChanges) == 0 || pi.Handicap != userinfo.Handicap {
+		pi.HandicapChanges = append(pi.HandicapChanges, HandicapChange{Time: time, Handicap: userinfo.Handicap})
 	}
+	pi.Team = userinfo.Team
+	pi.Handicap = userinfo.Handicap
+}
+
+func initKillCountByMeans(d *Dialect) map[string]int {
+	killCountByMeans := make(map[string]int, len(d.means()))
+	for _, m := range d.means() {
+		killCountByMeans[m.Name] = 0
+	}
+	return killCountByMeans
 }
 
 type PlayersInfo struct {
@@ -260,6 +718,39 @@ type PlayersInfo struct {
 	DeathCountBySource   map[string]int
 	KillCountByMean      map[string]int
 	KillCountByPlayerTag map[string]int
+	Bot                  bool
+	// KillStreak is the number of kills since the last death of the player.
+	KillStreak     int
+	BestKillStreak int
+	Team           Team
+	Handicap       int
+	// TeamKillCount are the kills of teammates, they are left out of KillCount,
+	// KillCountByPlayerTag and KillCountByMean.
+	TeamKillCount int
+	// TeamDeathCountBySource are the deaths by each teammate.
+	TeamDeathCountBySource map[string]int
+	CTF                    CTFStats
+	// TeamChanges and HandicapChanges are the values of the player over the
+	// game, starting with the first userinfo of the player.
+	TeamChanges     []TeamChange
+	HandicapChanges []HandicapChange
+	// Weapons are the shots and hits by weapon family, only set by the
+	// Weapon_Stats and Hit lines of some dialects.
+	Weapons        map[string]*WeaponAccuracy `json:",omitempty"`
+	DamageGiven    int
+	DamageReceived int
+	// Pings are the pings of the player on the score lines of the game.
+	Pings []int `json:",omitempty"`
+}
+
+type TeamChange struct {
+	Time string
+	Team Team
+}
+
+type HandicapChange struct {
+	Time     string
+	Handicap int
 }
 
 type WorldKillStatus struct {
@@ -268,6 +759,38 @@ type WorldKillStatus struct {
 	KillCountByPlayerTag map[string]int
 }
 
+// Anomaly is a problem found while building a game that did not abort it.
+type Anomaly struct {
+	Time    string
+	Message string
+	Err     error `json:"-"`
+}
+
+// KillRecord is a kill on the timeline of a game.
+type KillRecord struct {
+	Time     string
+	KillerId int
+	Killer   string
+	Victim   string
+	Means    string
+	TeamKill bool `json:",omitempty"`
+}
+
+// ItemRecord is an item pickup on the timeline of a game.
+type ItemRecord struct {
+	Time     string
+	ClientId int
+	Username string
+	Item     string
+}
+
+// ChatRecord is a chat message on the timeline of a game.
+type ChatRecord struct {
+	Time     string
+	Username string
+	Message  string
+}
+
 type Game struct {
 	PlayersInfoById     map[int]*PlayersInfo
 	EndingReason        string
@@ -275,10 +798,85 @@ type Game struct {
 	WorldKillStatus     WorldKillStatus
 	KillCountByMeans    map[string]int
 	TotalKills          int
+	// TotalTeamKills are the kills among players of the same team.
+	TotalTeamKills int
+	Anomalies      []Anomaly
+	// ServerConfig holds the server variables sent on InitGame, such as mapname and g_gametype.
+	ServerConfig map[string]string
+	StartTime    string
+	EndTime      string
+	Kills        []KillRecord
+	Items        []ItemRecord
+	Chat         []ChatRecord
+	// CTF is the state of the flags, only set once a flag is touched.
+	CTF *CTFState `json:",omitempty"`
+	// TeamScore is the final score of the teams, only logged on team games.
+	TeamScore *TeamScore `json:",omitempty"`
+	// Shutdown is true when the end of the game was logged by ShutdownGame.
+	Shutdown bool
+	Tags     GameTag
+	// Awards are the game awards won by the players.
+	Awards []Award `json:",omitempty"`
+	// ScoringRules is the name of the rules used to score the players.
+	ScoringRules string
+	// Dialect is the name of the Dialect of the log of the game.
+	Dialect string
+}
+
+// ErrorPolicy defines how the GameScanner reacts to syntax and context errors.
+type ErrorPolicy uint8
+
+const (
+	// EPLenient skips bad lines and drops the game on context errors.
+	EPLenient ErrorPolicy = iota
+	// EPStrict aborts the scan on the first error.
+	EPStrict
+	// EPRecover keeps the game and records every problem as an Anomaly.
+	EPRecover
+)
+
+const (
+	UnknownPlayerId   = -1
+	UnknownPlayerName = "<unknown>"
+)
+
+// ScannerState is the partial state of a GameScanner, used to resume a scan
+// without reading the input again. Game is the game still open when the
+// state was taken and World its <world>.
+type ScannerState struct {
+	Offset             int64
+	ClientIdByUsername map[string]int
+	Game               *Game
+	World              *PlayersInfo `json:",omitempty"`
+	PendingAnomalies   []Anomaly
 }
 
 type GameScanner struct {
 	Scanner            *bufio.Scanner
-	buffer             *Event[any]
+	ErrorPolicy        ErrorPolicy
+	TagOptions         TagOptions
+	Scoring            ScoringRules
+	Awards             []AwardRule
+	buffer             *Event
 	clientIdByUsername map[string]int
+	pendingAnomalies   []Anomaly
+	openGame           *Game
+	openWorld          *PlayersInfo
+	handlers           []EventHandler
+	// Dialect forces the grammar of the log, nil detects it on each InitGame.
+	Dialect *Dialect
+	dialect *Dialect
+	// HoldPartialLine leaves a last line without a line break unread.
+	HoldPartialLine bool
+	// flagEvents are the flag actions of the last event, sent to the handlers
+	// after it.
+	flagEvents []FlagEvent
+	ctx        context.Context
+	// readErr is the read or context error that ended the scan.
+	readErr error
+	// offset is the position after the last line read, lineStart the position of its beginning.
+	offset    int64
+	lineStart int64
+	// onLine is called with every line read, before it is applied to a game.
+	onLine func(line string, event *Event, err error)
 }
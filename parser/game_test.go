@@ -0,0 +1,431 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const unknownKillerLog = `  0:00 InitGame: \mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:05 Kill: 1022 2 22: Ghost killed Isgalamido by MOD_RAILGUN
+  0:06 Kill: 1022 2 22: <world> killed Isgalamido by MOD_FALLING
+  0:07 Exit: Timelimit hit.
+  0:08 ShutdownGame:
+  0:09 InitGame: \mapname\q3dm17
+  0:10 ShutdownGame:
+`
+
+func newTestScanner(input string, policy ErrorPolicy) *GameScanner {
+	gs := InitScanner(bufio.NewScanner(strings.NewReader(input)))
+	gs.ErrorPolicy = policy
+	return gs
+}
+
+func TestGetGameLenient(t *testing.T) {
+	gs := newTestScanner(unknownKillerLog, EPLenient)
+
+	game, ok, err := gs.GetGame()
+	if !ok || game != nil {
+		t.Fatalf("Expected the game to be dropped, got game %+v ok %v", game, ok)
+	}
+	var ctxErr *ContextError
+	if !errors.As(err, &ctxErr) {
+		t.Fatalf("Expected context error, got %v", err)
+	}
+
+	// the scan carries on with the following lines
+	game, ok, err = gs.GetGame()
+	if !ok || err == nil || game != nil {
+		t.Fatalf("Expected exit of dropped game to fail, got game %+v ok %v err %v", game, ok, err)
+	}
+}
+
+func TestGetGameStrict(t *testing.T) {
+	gs := newTestScanner(unknownKillerLog, EPStrict)
+
+	game, ok, err := gs.GetGame()
+	if ok || game != nil || err == nil {
+		t.Fatalf("Expected scan to abort, got game %+v ok %v err %v", game, ok, err)
+	}
+
+	gs = newTestScanner("  0:00 InitGame: \\mapname\\q3dm17\n  0:01 Bad: line\n  0:08 ShutdownGame:\n", EPStrict)
+	_, ok, err = gs.GetGame()
+	var synErr *SyntaxError
+	if ok || !errors.As(err, &synErr) {
+		t.Fatalf("Expected syntax error to abort, got ok %v err %v", ok, err)
+	}
+}
+
+func TestGetGameRecover(t *testing.T) {
+	gs := newTestScanner(unknownKillerLog, EPRecover)
+
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil || game == nil {
+		t.Fatalf("Expected game to be kept, got game %+v ok %v err %v", game, ok, err)
+	}
+	if game.EndingReason != "Timelimit hit." {
+		t.Errorf("Expected ending reason to be kept, got %s", game.EndingReason)
+	}
+	if len(game.Anomalies) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %+v", game.Anomalies)
+	}
+	if game.Anomalies[0].Time != "0:05" {
+		t.Errorf("Expected anomaly at 0:05, got %s", game.Anomalies[0].Time)
+	}
+
+	unknown, ok := game.PlayersInfoById[UnknownPlayerId]
+	if !ok {
+		t.Fatalf("Expected unknown player on game")
+	}
+	if unknown.KillCount != 1 || unknown.KillCountByPlayerTag["Isgalamido"] != 1 {
+		t.Errorf("Expected kill attributed to unknown player, got %+v", unknown)
+	}
+	if victim := game.PlayersInfoById[2]; victim.DeathCount != 2 || victim.Score != -1 {
+		t.Errorf("Expected victim with 2 deaths and -1 score, got %+v", victim)
+	}
+	if game.TotalKills != 2 {
+		t.Errorf("Expected 2 kills, got %d", game.TotalKills)
+	}
+
+	game, ok, err = gs.GetGame()
+	if !ok || err != nil || game == nil || len(game.Anomalies) != 0 {
+		t.Fatalf("Expected second game without anomalies, got game %+v ok %v err %v", game, ok, err)
+	}
+}
+
+func TestGetGameRecoverOutsideGame(t *testing.T) {
+	input := "  0:00 Exit: Timelimit hit.\n  0:01 InitGame: \\mapname\\q3dm17\n  0:02 ShutdownGame:\n"
+	gs := newTestScanner(input, EPRecover)
+
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil || game == nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	if len(game.Anomalies) != 1 || game.Anomalies[0].Time != "0:00" {
+		t.Errorf("Expected pending anomaly to be attached to the game, got %+v", game.Anomalies)
+	}
+}
+
+func TestParseErrorPolicy(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected ErrorPolicy
+		fails    bool
+	}{
+		"Empty":   {input: "", expected: EPLenient},
+		"Lenient": {input: "lenient", expected: EPLenient},
+		"Strict":  {input: "strict", expected: EPStrict},
+		"Recover": {input: "recover", expected: EPRecover},
+		"Unknown": {input: "other", expected: EPLenient, fails: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := ParseErrorPolicy(test.input)
+			if (err != nil) != test.fails {
+				t.Errorf("Unexpected error result: %v", err)
+			}
+			if result != test.expected {
+				t.Errorf("Expected %v, but got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestGameScannerResume(t *testing.T) {
+	first := `  0:00 InitGame: \mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:02 ClientConnect: 3
+  0:02 ClientUserinfoChanged: 3 n\Mocinha\t\0
+  0:05 Kill: 1022 2 22: <world> killed Isgalamido by MOD_FALLING
+`
+	second := `  0:06 Kill: 2 3 7: Isgalamido killed Mocinha by MOD_ROCKET
+  0:07 Kill: 1022 3 22: <world> killed Mocinha by MOD_FALLING
+  0:08 ShutdownGame:
+`
+	gs := newTestScanner(first, EPLenient)
+	if game, ok, err := gs.GetGame(); ok || err != nil || game == nil {
+		t.Fatalf("Expected open game at the end of input, got game %+v ok %v err %v", game, ok, err)
+	}
+	state := gs.State()
+	if state.Offset != int64(len(first)) {
+		t.Errorf("Expected offset %d, got %d", len(first), state.Offset)
+	}
+
+	resumed := newTestScanner(second, EPLenient)
+	resumed.Restore(state)
+	game, ok, err := resumed.GetGame()
+	if !ok || err != nil || game == nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	if game.TotalKills != 3 || game.WorldKillStatus.KillCount != 2 {
+		t.Errorf("Expected 3 kills, 2 by world, got %d and %d", game.TotalKills, game.WorldKillStatus.KillCount)
+	}
+	if isgalamido := game.PlayersInfoById[2]; isgalamido.Score != 0 || isgalamido.KillCount != 1 {
+		t.Errorf("Unexpected state of resumed player %+v", isgalamido)
+	}
+	if resumed.State().Offset != state.Offset+int64(len(second)) {
+		t.Errorf("Expected offset to continue from the restored one, got %d", resumed.State().Offset)
+	}
+}
+
+func TestGameScannerResumePartialLine(t *testing.T) {
+	complete := `  0:00 InitGame: \mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:02 ClientConnect: 3
+  0:02 ClientUserinfoChanged: 3 n\Mocinha\t\0
+  0:05 Kill: 1022 2 22: <world> killed Isgalamido by MOD_FALLING
+`
+	partial := `  0:06 Kill: 2 3 7: Isgalamido kil`
+	rest := `led Mocinha by MOD_ROCKET
+  0:08 ShutdownGame:
+`
+	gs := newTestScanner(complete+partial, EPStrict)
+	gs.HoldPartialLine = true
+	gs.Scoring.Kill = 2
+	if game, ok, err := gs.GetGame(); ok || err != nil || game == nil {
+		t.Fatalf("Expected open game at the end of input, got game %+v ok %v err %v", game, ok, err)
+	}
+	state := gs.State()
+	if state.Offset != int64(len(complete)) {
+		t.Errorf("Expected offset %d before the partial line, got %d", len(complete), state.Offset)
+	}
+	if state.World == nil || state.World.Score != 2 {
+		t.Errorf("Expected the <world> with its score on the state, got %+v", state.World)
+	}
+
+	resumed := newTestScanner(partial+rest, EPStrict)
+	resumed.Scoring.Kill = 2
+	resumed.Restore(state)
+	game, ok, err := resumed.GetGame()
+	if !ok || err != nil || game == nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	if game.TotalKills != 2 || game.WorldKillStatus.KillCount != 1 {
+		t.Errorf("Expected 2 kills, 1 by world, got %d and %d", game.TotalKills, game.WorldKillStatus.KillCount)
+	}
+}
+
+func TestGameScannerStateBuffered(t *testing.T) {
+	input := "  0:00 InitGame: \\mapname\\q3dm17\n  0:05 InitGame: \\mapname\\q3dm17\n"
+	gs := newTestScanner(input, EPLenient)
+	if _, ok, _ := gs.GetGame(); !ok {
+		t.Fatalf("Expected game ended by the next InitGame")
+	}
+	// the second InitGame was read but not applied
+	if offset := gs.State().Offset; offset != int64(strings.Index(input, "\n")+1) {
+		t.Errorf("Expected offset at the buffered line, got %d", offset)
+	}
+}
+
+// failingReader returns the lines of input and then err.
+type failingReader struct {
+	input *strings.Reader
+	err   error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.input.Len() == 0 {
+		return 0, r.err
+	}
+	return r.input.Read(p)
+}
+
+func TestNewGameScannerLongLine(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+	input := "  0:00 InitGame: \\mapname\\q3dm17\\sv_hostname\\" + long + "\n  0:01 ShutdownGame:\n"
+
+	gs := NewGameScanner(context.Background(), strings.NewReader(input), DefaultScannerOptions())
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil || game == nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	if game.ServerConfig["sv_hostname"] != long {
+		t.Errorf("Expected the long hostname to be kept")
+	}
+
+	opts := DefaultScannerOptions()
+	opts.MaxLineSize = 1024
+	gs = NewGameScanner(context.Background(), strings.NewReader(input), opts)
+	_, ok, err = gs.GetGame()
+	if ok || !errors.Is(err, bufio.ErrTooLong) {
+		t.Fatalf("Expected %v, but got ok %v err %v", bufio.ErrTooLong, ok, err)
+	}
+}
+
+func TestNewGameScannerReadError(t *testing.T) {
+	readErr := errors.New("disk failure")
+	reader := &failingReader{input: strings.NewReader("  0:00 InitGame: \\mapname\\q3dm17\n  0:01 ClientConnect: 2\n"), err: readErr}
+
+	gs := NewGameScanner(context.Background(), reader, DefaultScannerOptions())
+	game, ok, err := gs.GetGame()
+	if ok || !errors.Is(err, readErr) {
+		t.Fatalf("Expected %v, but got ok %v err %v", readErr, ok, err)
+	}
+	if game == nil || len(game.PlayersInfoById) != 1 {
+		t.Errorf("Expected the open game with the player read, got %+v", game)
+	}
+}
+
+func TestNewGameScannerZeroOptions(t *testing.T) {
+	gs := NewGameScanner(context.Background(), strings.NewReader(unknownKillerLog), ScannerOptions{ErrorPolicy: EPRecover})
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	if game.ScoringRules != "default" || game.PlayersInfoById[2].Score != -1 {
+		t.Errorf("Expected the default scoring rules, but got %s with score %d", game.ScoringRules, game.PlayersInfoById[2].Score)
+	}
+	if !reflect.DeepEqual(gs.TagOptions, DefaultTagOptions()) || len(gs.Awards) != len(DefaultAwardRules()) {
+		t.Errorf("Expected the default tag options and awards, but got %+v and %d awards", gs.TagOptions, len(gs.Awards))
+	}
+}
+
+func TestNewGameScannerCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	gs := NewGameScanner(ctx, strings.NewReader(unknownKillerLog), DefaultScannerOptions())
+	cancel()
+
+	game, ok, err := gs.GetGame()
+	if ok || game != nil || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected %v, but got game %+v ok %v err %v", context.Canceled, game, ok, err)
+	}
+}
+
+func TestGames(t *testing.T) {
+	input := unknownKillerLog + "  0:11 InitGame: \\mapname\\q3dm6\n"
+	opts := DefaultScannerOptions()
+	opts.ErrorPolicy = EPRecover
+	gs := NewGameScanner(context.Background(), strings.NewReader(input), opts)
+
+	var maps []string
+	for result := range gs.Games() {
+		if result.Err != nil {
+			t.Fatalf("Expected no error, but got %v", result.Err)
+		}
+		maps = append(maps, result.Game.ServerConfig["mapname"])
+	}
+	expected := []string{"q3dm17", "q3dm17", "q3dm6"}
+	if strings.Join(maps, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, but got %v", expected, maps)
+	}
+
+	reader := &failingReader{input: strings.NewReader(unknownKillerLog), err: errors.New("disk failure")}
+	gs = NewGameScanner(context.Background(), reader, opts)
+	var last ScanResult
+	for result := range gs.Games() {
+		last = result
+	}
+	if last.Game != nil || last.Err == nil {
+		t.Errorf("Expected the read error last, but got %+v", last)
+	}
+}
+
+func TestGamesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := DefaultScannerOptions()
+	opts.ErrorPolicy = EPRecover
+	gs := NewGameScanner(ctx, strings.NewReader(unknownKillerLog), opts)
+
+	results := gs.Games()
+	<-results
+	cancel()
+	for range results {
+	}
+}
+
+func TestTeamAndHandicapHistory(t *testing.T) {
+	input := `  0:00 InitGame: \mapname\q3ctf1\g_gametype\4
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\1\hc\100
+  0:02 ClientUserinfoChanged: 2 n\Isgalamido\t\1\hc\100
+  0:03 ClientUserinfoChanged: 2 n\Isgalamido\t\2\hc\100
+  0:04 ClientUserinfoChanged: 2 n\Isgalamido\t\2\hc\50
+  0:05 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	pi := game.PlayersInfoById[2]
+	if pi.Team != TMBlue || pi.Handicap != 50 {
+		t.Errorf("Expected team blue and handicap 50, but got %v and %d", pi.Team, pi.Handicap)
+	}
+	expectedTeams := []TeamChange{{"0:01", TMRed}, {"0:03", TMBlue}}
+	if !reflect.DeepEqual(pi.TeamChanges, expectedTeams) {
+		t.Errorf("Expected %v, but got %v", expectedTeams, pi.TeamChanges)
+	}
+	expectedHandicaps := []HandicapChange{{"0:01", 100}, {"0:04", 50}}
+	if !reflect.DeepEqual(pi.HandicapChanges, expectedHandicaps) {
+		t.Errorf("Expected %v, but got %v", expectedHandicaps, pi.HandicapChanges)
+	}
+}
+
+func TestTeamKills(t *testing.T) {
+	input := `  0:00 InitGame: \mapname\q3ctf1\g_gametype\4
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\1
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\1
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Mocinha\t\2
+  0:02 Kill: 2 4 10: Isgalamido killed Mocinha by MOD_RAILGUN
+  0:03 Kill: 2 3 10: Isgalamido killed Zeh by MOD_RAILGUN
+  0:04 Kill: 2 4 10: Isgalamido killed Mocinha by MOD_RAILGUN
+  0:05 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	gs.Scoring.TeamKill = -3
+	gs.Scoring.Streaks = []StreakBonus{{Kills: 2, Bonus: 5}}
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	if game.TotalKills != 3 || game.TotalTeamKills != 1 {
+		t.Errorf("Expected 3 kills and 1 team kill, but got %d and %d", game.TotalKills, game.TotalTeamKills)
+	}
+	killer := game.PlayersInfoById[2]
+	// +1, -3 for the team kill, then +1 and +5 for the second enemy kill in a row
+	if killer.Score != 4 {
+		t.Errorf("Expected %v, but got %v", 4, killer.Score)
+	}
+	if killer.KillCount != 2 || killer.TeamKillCount != 1 || killer.BestKillStreak != 2 {
+		t.Errorf("Expected 2 kills, 1 team kill and a streak of 2, but got %+v", killer)
+	}
+	if killer.KillCountByPlayerTag["Zeh"] != 0 || killer.KillCountByMean["MOD_RAILGUN"] != 2 {
+		t.Errorf("Expected the team kill out of the kill counters, but got %v and %v", killer.KillCountByPlayerTag, killer.KillCountByMean)
+	}
+	if victim := game.PlayersInfoById[3]; victim.TeamDeathCountBySource["Isgalamido"] != 1 {
+		t.Errorf("Expected a team death by Isgalamido, but got %v", victim.TeamDeathCountBySource)
+	}
+	if game.Kills[0].TeamKill || !game.Kills[1].TeamKill {
+		t.Errorf("Expected only the second kill to be a team kill, but got %+v", game.Kills)
+	}
+}
+
+func TestScorePings(t *testing.T) {
+	input := `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:02 score: 0  ping: 40  client: 2 Isgalamido
+  0:03 score: 0  ping: 380  client: 2 Isgalamido
+  0:03 score: 0  ping: 10  client: 7 Gone
+  0:04 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	game, ok, err := gs.GetGame()
+	if !ok || err != nil {
+		t.Fatalf("Expected game, got game %+v ok %v err %v", game, ok, err)
+	}
+	if pings := game.PlayersInfoById[2].Pings; !reflect.DeepEqual(pings, []int{40, 380}) {
+		t.Errorf("Expected %v, but got %v", []int{40, 380}, pings)
+	}
+}
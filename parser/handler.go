@@ -0,0 +1,109 @@
+package parser
+
+// EventHandler receives the events of the games read by a GameScanner, in
+// the order of the log. Each callback is called after the event was applied
+// to the game, so game has the statistics up to that event. Events outside
+// of a game and events with errors are not delivered.
+//
+// Handlers should embed BaseHandler, so they only implement the callbacks
+// they need and keep compiling when new callbacks are added.
+type EventHandler interface {
+	OnGameStart(game *Game, time string, initGame InitGame)
+	OnKill(game *Game, time string, kill Kill)
+	OnItem(game *Game, time string, item Item)
+	OnConnect(game *Game, time string, clientConnect ClientConnect)
+	OnUserinfoChanged(game *Game, time string, userinfo ClientUserinfoChanged)
+	OnBegin(game *Game, time string, clientBegin ClientBegin)
+	OnDisconnect(game *Game, time string, clientDisconnect ClientDisconnect)
+	OnSay(game *Game, time string, say Say)
+	OnScore(game *Game, time string, score Score)
+	OnExit(game *Game, time string, exit Exit)
+	OnTeamScore(game *Game, time string, teamScore TeamScore)
+	OnHit(game *Game, time string, hit Hit)
+	OnWeaponStats(game *Game, time string, stats WeaponStats)
+	// OnModEvent receives the lines of the headers of a Dialect that are not
+	// parsed by this package.
+	OnModEvent(game *Game, time string, mod ModEvent)
+	// OnCTF receives the flag actions logged by the dialects with CTF lines.
+	OnCTF(game *Game, time string, ctf CTF)
+	// OnFlag is called after the event that moved a flag, on CTF games.
+	OnFlag(game *Game, time string, flag FlagEvent)
+	// OnGameEnd is called once when a game ends, on its ShutdownGame or on
+	// the InitGame of the next game. Games dropped by a context error under
+	// EPLenient do not end.
+	OnGameEnd(game *Game)
+	// OnInputEnd is called instead of OnGameEnd for the game left open by the
+	// end of the input. The game is provisional: it continues on the next
+	// GetGame, or after a Restore, and OnGameEnd is called when it ends.
+	OnInputEnd(game *Game)
+}
+
+// BaseHandler implements every callback of EventHandler doing nothing.
+type BaseHandler struct{}
+
+func (BaseHandler) OnGameStart(game *Game, time string, initGame InitGame)                    {}
+func (BaseHandler) OnKill(game *Game, time string, kill Kill)                                 {}
+func (BaseHandler) OnItem(game *Game, time string, item Item)                                 {}
+func (BaseHandler) OnConnect(game *Game, time string, clientConnect ClientConnect)            {}
+func (BaseHandler) OnUserinfoChanged(game *Game, time string, userinfo ClientUserinfoChanged) {}
+func (BaseHandler) OnBegin(game *Game, time string, clientBegin ClientBegin)                  {}
+func (BaseHandler) OnDisconnect(game *Game, time string, clientDisconnect ClientDisconnect)   {}
+func (BaseHandler) OnSay(game *Game, time string, say Say)                                    {}
+func (BaseHandler) OnScore(game *Game, time string, score Score)                              {}
+func (BaseHandler) OnExit(game *Game, time string, exit Exit)                                 {}
+func (BaseHandler) OnTeamScore(game *Game, time string, teamScore TeamScore)                  {}
+func (BaseHandler) OnHit(game *Game, time string, hit Hit)                                    {}
+func (BaseHandler) OnWeaponStats(game *Game, time string, stats WeaponStats)                  {}
+func (BaseHandler) OnModEvent(game *Game, time string, mod ModEvent)                          {}
+func (BaseHandler) OnCTF(game *Game, time string, ctf CTF)                                    {}
+func (BaseHandler) OnFlag(game *Game, time string, flag FlagEvent)                            {}
+func (BaseHandler) OnGameEnd(game *Game)                                                      {}
+func (BaseHandler) OnInputEnd(game *Game)                                                     {}
+
+// Register adds a handler to the scanner. Handlers are called in the order
+// they were registered.
+func (gs *GameScanner) Register(handler EventHandler) {
+	gs.handlers = append(gs.handlers, handler)
+}
+
+// dispatch delivers an event applied to game to the handlers.
+func (gs *GameScanner) dispatch(game *Game, event *Event) {
+	for _, h := range gs.handlers {
+		switch data := event.Data.(type) {
+		case InitGame:
+			h.OnGameStart(game, event.Time, data)
+		case Kill:
+			h.OnKill(game, event.Time, data)
+		case Item:
+			h.OnItem(game, event.Time, data)
+		case ClientConnect:
+			h.OnConnect(game, event.Time, data)
+		case ClientUserinfoChanged:
+			h.OnUserinfoChanged(game, event.Time, data)
+		case ClientBegin:
+			h.OnBegin(game, event.Time, data)
+		case ClientDisconnect:
+			h.OnDisconnect(game, event.Time, data)
+		case Say:
+			h.OnSay(game, event.Time, data)
+		case Score:
+			h.OnScore(game, event.Time, data)
+		case Exit:
+			h.OnExit(game, event.Time, data)
+		case TeamScore:
+			h.OnTeamScore(game, event.Time, data)
+		case Hit:
+			h.OnHit(game, event.Time, data)
+		case WeaponStats:
+			h.OnWeaponStats(game, event.Time, data)
+		case ModEvent:
+			h.OnModEvent(game, event.Time, data)
+		case CTF:
+			h.OnCTF(game, event.Time, data)
+		}
+		for _, flag := range gs.flagEvents {
+			h.OnFlag(game, event.Time, flag)
+		}
+	}
+	gs.flagEvents = nil
+}
@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// recordingHandler records the callbacks it receives, and counts the
+// gauntlet kills as a custom statistic.
+type recordingHandler struct {
+	BaseHandler
+	calls          []string
+	gauntletKills  map[string]int
+	scoreAtGameEnd int
+}
+
+func (h *recordingHandler) OnGameStart(game *Game, time string, initGame InitGame) {
+	h.calls = append(h.calls, "start "+initGame.ServerConfig["mapname"])
+}
+
+func (h *recordingHandler) OnConnect(game *Game, time string, clientConnect ClientConnect) {
+	h.calls = append(h.calls, "connect")
+}
+
+func (h *recordingHandler) OnKill(game *Game, time string, kill Kill) {
+	h.calls = append(h.calls, "kill "+kill.Means)
+	if kill.Means == "MOD_GAUNTLET" {
+		h.gauntletKills[kill.Killer]++
+	}
+}
+
+func (h *recordingHandler) OnExit(game *Game, time string, exit Exit) {
+	h.calls = append(h.calls, "exit")
+}
+
+func (h *recordingHandler) OnGameEnd(game *Game) {
+	h.calls = append(h.calls, "end")
+	h.scoreAtGameEnd = game.PlayersInfoById[2].Score
+}
+
+func TestEventHandler(t *testing.T) {
+	input := `  0:00 InitGame: \mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:02 Kill: 2 3 2: Isgalamido killed Zeh by MOD_GAUNTLET
+  0:03 Kill: 2 3 10: Isgalamido killed Zeh by MOD_RAILGUN
+  0:04 Kill: 2 9 10: Isgalamido killed Nobody by MOD_RAILGUN
+  0:05 Exit: Fraglimit hit.
+  0:05 ShutdownGame:
+  0:06 Kill: 2 3 2: Isgalamido killed Zeh by MOD_GAUNTLET
+`
+	gs := newTestScanner(input, EPRecover)
+	handler := &recordingHandler{gauntletKills: make(map[string]int)}
+	gs.Register(handler)
+	for _, ok, _ := gs.GetGame(); ok; _, ok, _ = gs.GetGame() {
+	}
+
+	expected := []string{
+		"start q3dm17",
+		"connect",
+		"connect",
+		"kill MOD_GAUNTLET",
+		"kill MOD_RAILGUN",
+		"kill MOD_RAILGUN",
+		"exit",
+		"end",
+	}
+	if !reflect.DeepEqual(handler.calls, expected) {
+		t.Errorf("Expected %v, but got %v", expected, handler.calls)
+	}
+	if handler.gauntletKills["Isgalamido"] != 1 {
+		t.Errorf("Expected 1 gauntlet kill, but got %d", handler.gauntletKills["Isgalamido"])
+	}
+	if handler.scoreAtGameEnd != 3 {
+		t.Errorf("Expected score 3 at the game end, but got %d", handler.scoreAtGameEnd)
+	}
+}
+
+// endRecorder records the game ends and the dialect events.
+type endRecorder struct {
+	BaseHandler
+	calls []string
+}
+
+func (h *endRecorder) OnHit(game *Game, time string, hit Hit) {
+	h.calls = append(h.calls, "hit "+hit.Weapon)
+}
+
+func (h *endRecorder) OnWeaponStats(game *Game, time string, stats WeaponStats) {
+	h.calls = append(h.calls, "weapon stats")
+}
+
+func (h *endRecorder) OnModEvent(game *Game, time string, mod ModEvent) {
+	h.calls = append(h.calls, "mod "+mod.Name)
+}
+
+func (h *endRecorder) OnGameEnd(game *Game) {
+	h.calls = append(h.calls, "end "+game.EndTime)
+}
+
+func (h *endRecorder) OnInputEnd(game *Game) {
+	h.calls = append(h.calls, "input end "+game.EndTime)
+}
+
+func TestEventHandlerDialectEvents(t *testing.T) {
+	input := `  0:00 InitGame: \gamename\q3urt42\mapname\ut4_turnpike
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\1\r\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\2\r\0
+  0:02 Hit: 3 2 1 8: Isgalamido hit Zeh in the Head
+  0:02 Radio: 3 - 7 - 2 - "Spawn" - "Need backup"
+  0:03 ShutdownGame:
+  0:04 InitGame: \gamename\osp\mapname\q3dm17
+  0:05 ClientConnect: 2
+  0:05 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:06 Weapon_Stats: 2 MachineGun:2:10:0:0 Given:100 Recvd:50
+  0:07 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	handler := &endRecorder{}
+	gs.Register(handler)
+	for _, ok, _ := gs.GetGame(); ok; _, ok, _ = gs.GetGame() {
+	}
+
+	expected := []string{"hit lr300", "mod Radio:", "end 0:03", "weapon stats", "end 0:07"}
+	if !reflect.DeepEqual(handler.calls, expected) {
+		t.Errorf("Expected %v, but got %v", expected, handler.calls)
+	}
+}
+
+func TestEventHandlerInputEnd(t *testing.T) {
+	first := `  0:00 InitGame: \mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+`
+	second := `  0:02 Kill: 1022 2 22: <world> killed Isgalamido by MOD_FALLING
+  0:03 ShutdownGame:
+`
+	gs := newTestScanner(first, EPStrict)
+	handler := &endRecorder{}
+	gs.Register(handler)
+	gs.GetGame()
+
+	// the resumed game ends only once
+	resumed := newTestScanner(second, EPStrict)
+	resumed.Register(handler)
+	resumed.Restore(gs.State())
+	for _, ok, _ := resumed.GetGame(); ok; _, ok, _ = resumed.GetGame() {
+	}
+
+	expected := []string{"input end 0:01", "end 0:03"}
+	if !reflect.DeepEqual(handler.calls, expected) {
+		t.Errorf("Expected %v, but got %v", expected, handler.calls)
+	}
+}
@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const maxHealthProblems = 100
+
+// HealthProblem is a sample of a problem found while checking a log.
+type HealthProblem struct {
+	Line    int `json:",omitempty"`
+	Kind    string
+	Message string
+}
+
+// HealthReport summarizes the health of a log without building game reports.
+type HealthReport struct {
+	Lines                   int
+	Games                   int
+	CountByHeader           map[string]int
+	UnknownHeaders          map[string]int
+	SyntaxErrors            int
+	SyntaxErrorsByHeader    map[string]int
+	ContextErrors           int
+	ContextErrorsByHeader   map[string]int
+	GamesWithoutShutdown    int
+	UnexpectedShutdownGames int
+	// DuplicateInitGames counts InitGame lines found while a game was still open.
+	DuplicateInitGames int
+	// TimeRegressions counts lines whose time is lower than the previous line of the same game.
+	TimeRegressions int
+	Problems        []HealthProblem
+}
+
+// CheckHealth scans the whole log collecting the health statistics. Games
+// are built with the EPRecover policy so context errors do not hide the rest
+// of the game. A read error ends the scan, the report then has the statistics
+// of the lines read before it.
+func CheckHealth(scanner *bufio.Scanner) (*HealthReport, error) {
+	report := &HealthReport{
+		CountByHeader:         make(map[string]int),
+		UnknownHeaders:        make(map[string]int),
+		SyntaxErrorsByHeader:  make(map[string]int),
+		ContextErrorsByHeader: make(map[string]int),
+	}
+
+	gs := InitScanner(scanner)
+	gs.ErrorPolicy = EPRecover
+
+	gameOpen := false
+	var lastTime time.Duration
+	gs.onLine = func(line string, event *Event, err error) {
+		report.Lines++
+		if err != nil {
+			report.SyntaxErrors++
+			var synErr *SyntaxError
+			if errors.As(err, &synErr) {
+				report.SyntaxErrorsByHeader[synErr.Header().String()]++
+				if synErr.Header() == LHUnknown {
+					report.CountByHeader[LHUnknown.String()]++
+					if words := strings.Fields(line); len(words) > 1 {
+						report.UnknownHeaders[words[1]]++
+					}
+				}
+			}
+			report.addProblem(report.Lines, "syntax", err.Error())
+			return
+		}
+
+		report.CountByHeader[event.HeaderType.String()]++
+
+		switch event.HeaderType {
+		case LHInitGame:
+			if gameOpen {
+				report.DuplicateInitGames++
+				report.GamesWithoutShutdown++
+				report.addProblem(report.Lines, "duplicate InitGame", "InitGame found before the ShutdownGame of the previous game")
+			}
+			gameOpen = true
+			lastTime = 0
+		case LHShutdownGame:
+			gameOpen = false
+		}
+
+		if t, err := ParseTime(event.Time); err == nil && gameOpen {
+			if t < lastTime && event.HeaderType != LHInitGame {
+				report.TimeRegressions++
+				report.addProblem(report.Lines, "time regression", fmt.Sprintf("time %s is lower than previous time %s", event.Time, formatTime(lastTime)))
+			}
+			lastTime = t
+		}
+	}
+
+	var readErr error
+	for {
+		game, ok, err := gs.GetGame()
+		if game != nil {
+			report.addGame(game)
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+		if !ok {
+			break
+		}
+	}
+	for _, a := range gs.pendingAnomalies {
+		report.addAnomaly(a)
+	}
+	if gameOpen {
+		report.GamesWithoutShutdown++
+	}
+	return report, readErr
+}
+
+func (hr *HealthReport) addGame(game *Game) {
+	hr.Games++
+	if game.EndingReason == "SERVER_UNEXPECTED_SHUTDOWN" {
+		hr.UnexpectedShutdownGames++
+	}
+	for _, a := range game.Anomalies {
+		hr.addAnomaly(a)
+	}
+}
+
+// addAnomaly counts the context errors of a game. Syntax errors are counted
+// while reading the lines.
+func (hr *HealthReport) addAnomaly(a Anomaly) {
+	var ctxErr *ContextError
+	if errors.As(a.Err, &ctxErr) {
+		hr.ContextErrors++
+		hr.ContextErrorsByHeader[ctxErr.Header().String()]++
+		hr.addProblem(0, "context", fmt.Sprintf("%s %s", a.Time, a.Message))
+	}
+}
+
+// addProblem keeps a sample of the problems found. Context errors are only
+// known once the game ends, so they are reported without a line.
+func (hr *HealthReport) addProblem(line int, kind string, message string) {
+	if len(hr.Problems) < maxHealthProblems {
+		hr.Problems = append(hr.Problems, HealthProblem{
+			Line:    line,
+			Kind:    kind,
+			Message: message,
+		})
+	}
+}
+
+func formatTime(t time.Duration) string {
+	return fmt.Sprintf("%d:%02d", int(t.Minutes()), int(t.Seconds())%60)
+}
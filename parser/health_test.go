@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckHealth(t *testing.T) {
+	input := `  0:00 InitGame: \mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:05 Kill: 1022 2 22: Ghost killed Isgalamido by MOD_RAILGUN
+  0:04 Item: 2 weapon_rocketlauncher
+  0:06 Vote: 2 map q3dm6
+  0:07 Item: 2 invalid
+  0:00 InitGame: \mapname\q3dm17
+  0:10 ShutdownGame:
+  0:00 InitGame: \mapname\q3dm17
+  0:05 Exit: Fraglimit hit.
+`
+	report, err := CheckHealth(bufio.NewScanner(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]int{
+		"Lines":                   11,
+		"Games":                   3,
+		"SyntaxErrors":            2,
+		"ContextErrors":           1,
+		"GamesWithoutShutdown":    2,
+		"UnexpectedShutdownGames": 2,
+		"DuplicateInitGames":      1,
+		"TimeRegressions":         1,
+		"InitGame":                3,
+	}
+	result := map[string]int{
+		"Lines":                   report.Lines,
+		"Games":                   report.Games,
+		"SyntaxErrors":            report.SyntaxErrors,
+		"ContextErrors":           report.ContextErrors,
+		"GamesWithoutShutdown":    report.GamesWithoutShutdown,
+		"UnexpectedShutdownGames": report.UnexpectedShutdownGames,
+		"DuplicateInitGames":      report.DuplicateInitGames,
+		"TimeRegressions":         report.TimeRegressions,
+		"InitGame":                report.CountByHeader["InitGame"],
+	}
+	for name, value := range expected {
+		if result[name] != value {
+			t.Errorf("Expected %s to be %d, but got %d", name, value, result[name])
+		}
+	}
+
+	if report.UnknownHeaders["Vote:"] != 1 {
+		t.Errorf("Expected unknown header Vote:, got %+v", report.UnknownHeaders)
+	}
+	if report.SyntaxErrorsByHeader["Item"] != 1 || report.SyntaxErrorsByHeader["Unknown"] != 1 {
+		t.Errorf("Unexpected syntax errors by header %+v", report.SyntaxErrorsByHeader)
+	}
+	if report.ContextErrorsByHeader["Kill"] != 1 {
+		t.Errorf("Unexpected context errors by header %+v", report.ContextErrorsByHeader)
+	}
+}
+
+func TestCheckHealthReadError(t *testing.T) {
+	reader := &failingReader{input: strings.NewReader(unknownKillerLog), err: errors.New("disk failure")}
+	report, err := CheckHealth(bufio.NewScanner(reader))
+	if err == nil {
+		t.Fatalf("Expected the read error")
+	}
+	if report == nil || report.Lines != 9 || report.Games != 2 {
+		t.Errorf("Expected the report of the 9 lines and 2 games read, but got %+v", report)
+	}
+}
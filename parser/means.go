@@ -0,0 +1,104 @@
+package parser
+
+import "strings"
+
+// MeansClass classifies what caused a death.
+type MeansClass uint8
+
+const (
+	// MCWeapon is a death by a weapon or holdable item of a player.
+	MCWeapon MeansClass = iota
+	// MCEnvironment is a death by the map, such as lava or falling.
+	MCEnvironment
+	// MCSelf is a death by the kill command.
+	MCSelf
+	// MCOther is a death that is neither, such as a telefrag, or a means
+	// missing from the catalog.
+	MCOther
+)
+
+// MeansOfDeath describes a MOD_* value of the Kill lines.
+type MeansOfDeath struct {
+	// Id is the number logged on the Kill lines before the names.
+	Id   int
+	Name string
+	// Family groups the means of the same weapon, it is the weapon classname
+	// without the weapon_ prefix, e.g. rocketlauncher for MOD_ROCKET and
+	// MOD_ROCKET_SPLASH. Other means are their own family.
+	Family      string
+	Class       MeansClass
+	DisplayName string
+}
+
+// meansCatalog follows the meansOfDeath_t enum of Quake 3, the ids logged by
+// the baseq3 game code.
+var meansCatalog = []MeansOfDeath{
+	{0, "MOD_UNKNOWN", "unknown", MCOther, "Unknown"},
+	{1, "MOD_SHOTGUN", "shotgun", MCWeapon, "Shotgun"},
+	{2, "MOD_GAUNTLET", "gauntlet", MCWeapon, "Gauntlet"},
+	{3, "MOD_MACHINEGUN", "machinegun", MCWeapon, "Machinegun"},
+	{4, "MOD_GRENADE", "grenadelauncher", MCWeapon, "Grenade"},
+	{5, "MOD_GRENADE_SPLASH", "grenadelauncher", MCWeapon, "Grenade splash"},
+	{6, "MOD_ROCKET", "rocketlauncher", MCWeapon, "Rocket"},
+	{7, "MOD_ROCKET_SPLASH", "rocketlauncher", MCWeapon, "Rocket splash"},
+	{8, "MOD_PLASMA", "plasmagun", MCWeapon, "Plasma"},
+	{9, "MOD_PLASMA_SPLASH", "plasmagun", MCWeapon, "Plasma splash"},
+	{10, "MOD_RAILGUN", "railgun", MCWeapon, "Railgun"},
+	{11, "MOD_LIGHTNING", "lightning", MCWeapon, "Lightning gun"},
+	{12, "MOD_BFG", "bfg", MCWeapon, "BFG10K"},
+	{13, "MOD_BFG_SPLASH", "bfg", MCWeapon, "BFG10K splash"},
+	{14, "MOD_WATER", "water", MCEnvironment, "Drowned"},
+	{15, "MOD_SLIME", "slime", MCEnvironment, "Slime"},
+	{16, "MOD_LAVA", "lava", MCEnvironment, "Lava"},
+	{17, "MOD_CRUSH", "crush", MCEnvironment, "Crushed"},
+	{18, "MOD_TELEFRAG", "telefrag", MCOther, "Telefrag"},
+	{19, "MOD_FALLING", "falling", MCEnvironment, "Falling"},
+	{20, "MOD_SUICIDE", "suicide", MCSelf, "Suicide"},
+	{21, "MOD_TARGET_LASER", "target_laser", MCEnvironment, "Laser"},
+	{22, "MOD_TRIGGER_HURT", "trigger_hurt", MCEnvironment, "Map hazard"},
+	{23, "MOD_GRAPPLE", "grapplinghook", MCWeapon, "Grappling hook"},
+}
+
+// missionpackMeans are the means of the game code built with MISSIONPACK,
+// Team Arena and OpenArena, which adds the team arena means before
+// MOD_GRAPPLE and so logs other ids from 23 on.
+var missionpackMeans = append(append([]MeansOfDeath(nil), meansCatalog[:23]...), []MeansOfDeath{
+	{23, "MOD_NAIL", "nailgun", MCWeapon, "Nailgun"},
+	{24, "MOD_CHAINGUN", "chaingun", MCWeapon, "Chaingun"},
+	{25, "MOD_PROXIMITY_MINE", "prox_launcher", MCWeapon, "Proximity mine"},
+	{26, "MOD_KAMIKAZE", "kamikaze", MCWeapon, "Kamikaze"},
+	{27, "MOD_JUICED", "juiced", MCOther, "Juiced"},
+	{28, "MOD_GRAPPLE", "grapplinghook", MCWeapon, "Grappling hook"},
+}...)
+
+var meansByName = func() map[string]MeansOfDeath {
+	byName := make(map[string]MeansOfDeath, len(meansCatalog))
+	for _, m := range meansCatalog {
+		byName[m.Name] = m
+	}
+	return byName
+}()
+
+// MeansCatalog returns the means of death of baseq3, ordered by id. See
+// Dialect.MeansCatalog for the ids of other game codes.
+func MeansCatalog() []MeansOfDeath {
+	return append([]MeansOfDeath(nil), meansCatalog...)
+}
+
+// LookupMeans returns the means of death by its name, from the catalog of
+// baseq3 or of the registered dialects. Means missing from the catalogs are
+// returned as MCOther on their own family, with false.
+func LookupMeans(name string) (MeansOfDeath, bool) {
+	if m, ok := meansByName[name]; ok {
+		return m, true
+	}
+	for _, d := range dialects {
+		for _, m := range d.Means {
+			if m.Name == name {
+				return m, true
+			}
+		}
+	}
+	family := strings.ToLower(strings.TrimPrefix(name, "MOD_"))
+	return MeansOfDeath{Id: -1, Name: name, Family: family, Class: MCOther, DisplayName: name}, false
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMeansCatalog(t *testing.T) {
+	tests := map[string]struct {
+		catalog []MeansOfDeath
+		grapple int
+	}{
+		"Baseq3":      {catalog: MeansCatalog(), grapple: 23},
+		"Missionpack": {catalog: DialectMissionpack.MeansCatalog(), grapple: 28},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			for i, m := range test.catalog {
+				if m.Id != i {
+					t.Errorf("Expected %s to have id %d, but got %d", m.Name, i, m.Id)
+				}
+			}
+			if m := test.catalog[test.grapple]; m.Name != "MOD_GRAPPLE" {
+				t.Errorf("Expected %v, but got %v", "MOD_GRAPPLE", m.Name)
+			}
+		})
+	}
+	if d := DetectDialect(map[string]string{"gamename": "missionpack"}); d != DialectMissionpack {
+		t.Errorf("Expected %v, but got %v", DialectMissionpack.Name, d.Name)
+	}
+}
+
+func TestLookupMeans(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		family string
+		class  MeansClass
+		known  bool
+	}{
+		"Rocket":       {input: "MOD_ROCKET", family: "rocketlauncher", class: MCWeapon, known: true},
+		"RocketSplash": {input: "MOD_ROCKET_SPLASH", family: "rocketlauncher", class: MCWeapon, known: true},
+		"TriggerHurt":  {input: "MOD_TRIGGER_HURT", family: "trigger_hurt", class: MCEnvironment, known: true},
+		"Suicide":      {input: "MOD_SUICIDE", family: "suicide", class: MCSelf, known: true},
+		"Telefrag":     {input: "MOD_TELEFRAG", family: "telefrag", class: MCOther, known: true},
+		"Unknown":      {input: "MOD_KNIFE", family: "knife", class: MCOther, known: false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m, known := LookupMeans(test.input)
+			if known != test.known || m.Family != test.family || m.Class != test.class {
+				t.Errorf("Expected %s %s %v, but got %s %s %v", test.family, test.class, test.known, m.Family, m.Class, known)
+			}
+		})
+	}
+}
+
+func TestMeansClassText(t *testing.T) {
+	for _, class := range []MeansClass{MCWeapon, MCEnvironment, MCSelf, MCOther} {
+		data, err := json.Marshal(class)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		var read MeansClass
+		if err := json.Unmarshal(data, &read); err != nil || read != class {
+			t.Errorf("Expected %v, but got %v (error %v)", class, read, err)
+		}
+	}
+	var read MeansClass
+	if err := json.Unmarshal([]byte(`"gravity"`), &read); err == nil {
+		t.Errorf("Expected an error for an unknown class")
+	}
+}
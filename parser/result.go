@@ -0,0 +1,214 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EndingCondition is how a game ended.
+type EndingCondition uint8
+
+const (
+	ECUnknown EndingCondition = iota
+	ECFraglimit
+	ECTimelimit
+	ECCapturelimit
+	// ECVote is a game ended by a vote of the players.
+	ECVote
+	// ECShutdown is a game ended by ShutdownGame without Exit, such as on a
+	// map change by the server admin.
+	ECShutdown
+	// ECAbandoned is a game whose end was never logged, it was followed by
+	// another InitGame or by the end of the log.
+	ECAbandoned
+)
+
+// teamGameTypes are the g_gametype values played by teams: team deathmatch
+// and capture the flag.
+var teamGameTypes = map[string]bool{
+	"3": true,
+	"4": true,
+}
+
+// Placement is the final position of a player. Players with the same score
+// share the position and the next position is skipped, e.g. 1, 1, 3.
+type Placement struct {
+	Place int
+	Name  string
+	Score int
+}
+
+// Result is the outcome of a game.
+type Result struct {
+	Condition EndingCondition
+	// Winner is the player on first place of a free for all game, empty on
+	// team games and on ties.
+	Winner string `json:",omitempty"`
+	// WinningTeam is red or blue on team games, empty on draws.
+	WinningTeam string     `json:",omitempty"`
+	TeamScore   *TeamScore `json:",omitempty"`
+	Placements  []Placement
+	// Notes are the disagreements between the ending and the limits of
+	// the server config, such as a fraglimit hit below the fraglimit.
+	Notes []string `json:",omitempty"`
+}
+
+// IsTeamGame reports if the game is played by teams.
+func (game *Game) IsTeamGame() bool {
+	return teamGameTypes[game.ServerConfig["g_gametype"]] || game.TeamScore != nil
+}
+
+// Result classifies the ending of the game and ranks its players. The
+// ending is checked against the fraglimit, timelimit and capturelimit sent
+// on InitGame.
+func (game *Game) Result() Result {
+	result := Result{
+		Condition:  game.endingCondition(),
+		TeamScore:  game.TeamScore,
+		Placements: game.placements(),
+	}
+
+	if game.IsTeamGame() {
+		if ts := game.TeamScore; ts != nil {
+			switch {
+			case ts.Red > ts.Blue:
+				result.WinningTeam = "red"
+			case ts.Blue > ts.Red:
+				result.WinningTeam = "blue"
+			}
+		}
+	} else if len(result.Placements) == 1 || (len(result.Placements) > 1 && result.Placements[1].Place != 1) {
+		result.Winner = result.Placements[0].Name
+	}
+
+	result.Notes = game.checkLimits(result)
+	return result
+}
+
+func (game *Game) endingCondition() EndingCondition {
+	reason := strings.ToLower(game.EndingReason)
+	switch {
+	case strings.HasPrefix(reason, "fraglimit"):
+		return ECFraglimit
+	case strings.HasPrefix(reason, "timelimit"):
+		return ECTimelimit
+	case strings.HasPrefix(reason, "capturelimit"):
+		return ECCapturelimit
+	case strings.Contains(reason, "vote"):
+		return ECVote
+	case game.Shutdown:
+		return ECShutdown
+	case game.EndingReason == "" || game.EndingReason == "SERVER_UNEXPECTED_SHUTDOWN":
+		return ECAbandoned
+	}
+	return ECUnknown
+}
+
+// placements ranks the players by name, including the ones that left the
+// game. A player that reconnected has the score of each connection summed.
+func (game *Game) placements() []Placement {
+	scoreByName := make(map[string]int)
+	add := func(pi *PlayersInfo) {
+		if pi.Id == 0 || pi.Id == UnknownPlayerId || pi.Username == "" {
+			return
+		}
+		scoreByName[pi.Username] += pi.Score
+	}
+	for _, pi := range game.PlayersInfoById {
+		add(pi)
+	}
+	for _, pi := range game.DisconnectedPlayers {
+		add(pi)
+	}
+	var placements []Placement
+	for name, score := range scoreByName {
+		placements = append(placements, Placement{Name: name, Score: score})
+	}
+	sort.Slice(placements, func(i, j int) bool {
+		if placements[i].Score != placements[j].Score {
+			return placements[i].Score > placements[j].Score
+		}
+		return placements[i].Name < placements[j].Name
+	})
+	for i := range placements {
+		placements[i].Place = i + 1
+		if i > 0 && placements[i].Score == placements[i-1].Score {
+			placements[i].Place = placements[i-1].Place
+		}
+	}
+	return placements
+}
+
+// topScore is the score that counts for the fraglimit: the best team on team
+// games and the best player otherwise.
+func (game *Game) topScore(result Result) (int, bool) {
+	if result.TeamScore != nil {
+		return max(result.TeamScore.Red, result.TeamScore.Blue), true
+	}
+	if len(result.Placements) > 0 && !game.IsTeamGame() {
+		return result.Placements[0].Score, true
+	}
+	return 0, false
+}
+
+func (game *Game) checkLimits(result Result) []string {
+	var notes []string
+	fraglimit := game.limit("fraglimit")
+	timelimit := game.limit("timelimit")
+	capturelimit := game.limit("capturelimit")
+	top, hasTop := game.topScore(result)
+
+	switch result.Condition {
+	case ECFraglimit:
+		if fraglimit == 0 {
+			notes = append(notes, "fraglimit hit without a fraglimit on the server config")
+		} else if hasTop && game.ServerConfig["g_gametype"] != "4" && top < fraglimit {
+			notes = append(notes, fmt.Sprintf("fraglimit hit with top score %d below the fraglimit %d", top, fraglimit))
+		}
+	case ECCapturelimit:
+		if capturelimit == 0 {
+			notes = append(notes, "capturelimit hit without a capturelimit on the server config")
+		} else if result.TeamScore != nil && top < capturelimit {
+			notes = append(notes, fmt.Sprintf("capturelimit hit with team score %d below the capturelimit %d", top, capturelimit))
+		}
+	case ECTimelimit:
+		if timelimit == 0 {
+			notes = append(notes, "timelimit hit without a timelimit on the server config")
+		} else if d, ok := game.Duration(); ok && int(d.Minutes()) < timelimit {
+			minutes := int(d.Minutes())
+			notes = append(notes, fmt.Sprintf("timelimit hit after %d minutes, below the timelimit %d", minutes, timelimit))
+		}
+	case ECShutdown, ECAbandoned:
+		if fraglimit > 0 && hasTop && top >= fraglimit {
+			notes = append(notes, fmt.Sprintf("top score %d reached the fraglimit %d without an Exit", top, fraglimit))
+		}
+	}
+	return notes
+}
+
+// limit returns a limit of the server config, 0 when it is missing or
+// disabled.
+func (game *Game) limit(key string) int {
+	value, err := strconv.Atoi(game.ServerConfig[key])
+	if err != nil || value < 0 {
+		return 0
+	}
+	return value
+}
+
+// Duration is the time between the start and the end of the game, it is not
+// known when the clock of the log was reset during the game.
+func (game *Game) Duration() (time.Duration, bool) {
+	start, err := ParseTime(game.StartTime)
+	if err != nil {
+		return 0, false
+	}
+	end, err := ParseTime(game.EndTime)
+	if err != nil || end < start {
+		return 0, false
+	}
+	return end - start, true
+}
@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGameResult(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected Result
+	}{
+		"FraglimitWithTie": {
+			input: `  0:00 InitGame: \g_gametype\0\fraglimit\2\timelimit\15\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Mal\t\0
+  0:02 Kill: 2 4 7: Isgalamido killed Mal by MOD_ROCKET_SPLASH
+  0:03 Kill: 2 4 7: Isgalamido killed Mal by MOD_ROCKET_SPLASH
+  0:04 Kill: 3 4 7: Zeh killed Mal by MOD_ROCKET_SPLASH
+  0:05 Exit: Fraglimit hit.
+  0:05 ShutdownGame:
+`,
+			expected: Result{
+				Condition: ECFraglimit,
+				Winner:    "Isgalamido",
+				Placements: []Placement{
+					{Place: 1, Name: "Isgalamido", Score: 2},
+					{Place: 2, Name: "Zeh", Score: 1},
+					{Place: 3, Name: "Mal", Score: 0},
+				},
+			},
+		},
+		"TiedFirstPlace": {
+			input: `  0:00 InitGame: \g_gametype\0\fraglimit\20\timelimit\1\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Mal\t\0
+  0:02 Kill: 2 4 7: Isgalamido killed Mal by MOD_ROCKET_SPLASH
+  0:04 Kill: 3 4 7: Zeh killed Mal by MOD_ROCKET_SPLASH
+  1:00 Exit: Timelimit hit.
+  1:00 ShutdownGame:
+`,
+			expected: Result{
+				Condition: ECTimelimit,
+				Placements: []Placement{
+					{Place: 1, Name: "Isgalamido", Score: 1},
+					{Place: 1, Name: "Zeh", Score: 1},
+					{Place: 3, Name: "Mal", Score: 0},
+				},
+			},
+		},
+		"LeaderDisconnected": {
+			input: `  0:00 InitGame: \g_gametype\0\fraglimit\20\timelimit\1\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Mal\t\0
+  0:02 Kill: 2 4 7: Isgalamido killed Mal by MOD_ROCKET_SPLASH
+  0:03 Kill: 2 4 7: Isgalamido killed Mal by MOD_ROCKET_SPLASH
+  0:03 Kill: 2 4 7: Isgalamido killed Mal by MOD_ROCKET_SPLASH
+  0:04 ClientDisconnect: 2
+  0:05 Kill: 3 4 7: Zeh killed Mal by MOD_ROCKET_SPLASH
+  0:06 ClientDisconnect: 3
+  0:07 ClientConnect: 5
+  0:07 ClientUserinfoChanged: 5 n\Zeh\t\0
+  0:08 Kill: 5 4 7: Zeh killed Mal by MOD_ROCKET_SPLASH
+  1:00 Exit: Timelimit hit.
+  1:00 ShutdownGame:
+`,
+			expected: Result{
+				Condition: ECTimelimit,
+				Winner:    "Isgalamido",
+				Placements: []Placement{
+					{Place: 1, Name: "Isgalamido", Score: 3},
+					{Place: 2, Name: "Zeh", Score: 2},
+					{Place: 3, Name: "Mal", Score: 0},
+				},
+			},
+		},
+		"CapturelimitTeams": {
+			input: `  0:00 InitGame: \g_gametype\4\capturelimit\8\mapname\q3ctf1
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\1
+  0:05 Exit: Capturelimit hit.
+  0:05 red:2  blue:8
+  0:05 ShutdownGame:
+`,
+			expected: Result{
+				Condition:   ECCapturelimit,
+				WinningTeam: "blue",
+				TeamScore:   &TeamScore{Red: 2, Blue: 8},
+				Placements:  []Placement{{Place: 1, Name: "Isgalamido", Score: 0}},
+			},
+		},
+		"FraglimitBelowLimit": {
+			input: `  0:00 InitGame: \g_gametype\0\fraglimit\20\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:05 Exit: Fraglimit hit.
+  0:05 ShutdownGame:
+`,
+			expected: Result{
+				Condition:  ECFraglimit,
+				Winner:     "Isgalamido",
+				Placements: []Placement{{Place: 1, Name: "Isgalamido", Score: 0}},
+				Notes:      []string{"fraglimit hit with top score 0 below the fraglimit 20"},
+			},
+		},
+		"Shutdown": {
+			input: `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:05 ShutdownGame:
+`,
+			expected: Result{Condition: ECShutdown},
+		},
+		"Abandoned": {
+			input: `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:05 ClientConnect: 2
+`,
+			expected: Result{Condition: ECAbandoned},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			game, _, err := newTestScanner(test.input, EPStrict).GetGame()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			result := game.Result()
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("Expected %+v, but got %+v", test.expected, result)
+			}
+		})
+	}
+}
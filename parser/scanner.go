@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Scanner reads the events of a log one line at a time, without building
+// games. Empty lines are skipped and lines up to DefaultMaxLineSize are read.
+// The Dialect of the log is detected on each InitGame unless it is set.
+//
+//	s := parser.NewScanner(file)
+//	for s.Scan() {
+//		event, err := s.Event()
+//		if err != nil {
+//			continue
+//		}
+//		if kill, ok := event.Data.(parser.Kill); ok {
+//			...
+//		}
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+type Scanner struct {
+	// Dialect forces the grammar of the log, nil detects it.
+	Dialect *Dialect
+	dialect *Dialect
+	scanner *bufio.Scanner
+	line    string
+	event   *Event
+	err     error
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), DefaultMaxLineSize)
+	return &Scanner{scanner: scanner, dialect: DialectBaseq3}
+}
+
+// Scan advances to the next line of the log. It returns false at the end of
+// the input or on a read error, see Err.
+func (s *Scanner) Scan() bool {
+	for s.scanner.Scan() {
+		line := strings.TrimSpace(s.scanner.Text())
+		if line == "" {
+			continue
+		}
+		s.line = line
+		dialect := s.Dialect
+		if dialect == nil {
+			dialect = s.dialect
+		}
+		s.event, s.err = dialect.ParseLine(line)
+		if initGame, ok := s.event.Data.(InitGame); ok && s.err == nil {
+			s.dialect = DetectDialect(initGame.ServerConfig)
+		}
+		return true
+	}
+	s.line, s.event, s.err = "", nil, nil
+	return false
+}
+
+// Event returns the event of the current line, or the SyntaxError of a line
+// that could not be parsed.
+func (s *Scanner) Event() (*Event, error) {
+	return s.event, s.err
+}
+
+// Line returns the current line without surrounding spaces.
+func (s *Scanner) Line() string {
+	return s.line
+}
+
+// Err returns the first read error of the input.
+func (s *Scanner) Err() error {
+	return s.scanner.Err()
+}
@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	input := `  0:00 InitGame: \mapname\q3dm17
+
+  0:02 Kill: 2 3 2: Isgalamido killed Zeh by MOD_GAUNTLET
+  0:03 Kill: x
+  0:04 ShutdownGame:
+`
+	s := NewScanner(strings.NewReader(input))
+	var headers []LogHeader
+	var syntaxErrors int
+	for s.Scan() {
+		event, err := s.Event()
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) {
+			syntaxErrors++
+			if s.Line() != "0:03 Kill: x" {
+				t.Errorf("Expected %v, but got %v", "0:03 Kill: x", s.Line())
+			}
+			continue
+		}
+		if event.Data.Header() != event.HeaderType {
+			t.Errorf("Expected %v, but got %v", event.HeaderType, event.Data.Header())
+		}
+		if kill, ok := event.Data.(Kill); ok && kill.Means != "MOD_GAUNTLET" {
+			t.Errorf("Expected %v, but got %v", "MOD_GAUNTLET", kill.Means)
+		}
+		headers = append(headers, event.HeaderType)
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("Expected no read error, but got %v", err)
+	}
+
+	expected := []LogHeader{LHInitGame, LHKill, LHShutdownGame}
+	if len(headers) != len(expected) {
+		t.Fatalf("Expected %v, but got %v", expected, headers)
+	}
+	for i := range expected {
+		if headers[i] != expected[i] {
+			t.Errorf("Expected %v, but got %v", expected, headers)
+		}
+	}
+	if syntaxErrors != 1 {
+		t.Errorf("Expected %v, but got %v", 1, syntaxErrors)
+	}
+}
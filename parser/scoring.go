@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StreakBonus gives Bonus points to a player when it reaches Kills kills
+// without dying.
+type StreakBonus struct {
+	Kills int
+	Bonus int
+}
+
+// ScoringRules define the points of each kill. The default rules are the
+// ones of Quake 3: +1 for a kill, -1 for a kill of a teammate, -1 when
+// killed by <world> and nothing for suicides.
+type ScoringRules struct {
+	Name       string
+	Kill       int
+	Suicide    int
+	WorldDeath int
+	// TeamKill replaces Kill when the victim is on the killer team, without
+	// the weapon and streak bonuses. The teams come from the userinfo, so it
+	// only applies to team games.
+	TeamKill int
+	// WeaponBonus adds points to kills by a means of death, e.g. MOD_GAUNTLET.
+	WeaponBonus map[string]int
+	Streaks     []StreakBonus
+}
+
+func DefaultScoringRules() ScoringRules {
+	return ScoringRules{
+		Name:       "default",
+		Kill:       1,
+		Suicide:    0,
+		WorldDeath: -1,
+		TeamKill:   -1,
+	}
+}
+
+// LoadScoringRules reads scoring rules from a json file. Missing fields keep
+// the default value and the name defaults to the file name.
+func LoadScoringRules(path string) (ScoringRules, error) {
+	rules := DefaultScoringRules()
+	rules.Name = ""
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return rules, err
+	}
+	if rules.Name == "" {
+		rules.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return rules, nil
+}
+
+// killPoints are the points of a kill by means that makes the killer reach
+// streak kills without dying.
+func (r *ScoringRules) killPoints(means string, streak int) int {
+	points := r.Kill + r.WeaponBonus[means]
+	for _, s := range r.Streaks {
+		if s.Kills == streak {
+			points += s.Bonus
+		}
+	}
+	return points
+}
@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScoringRules(t *testing.T) {
+	input := `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:02 Kill: 2 3 7: Isgalamido killed Zeh by MOD_ROCKET_SPLASH
+  0:03 Kill: 2 3 2: Isgalamido killed Zeh by MOD_GAUNTLET
+  0:04 Kill: 1022 2 22: <world> killed Isgalamido by MOD_TRIGGER_HURT
+  0:05 Kill: 3 3 7: Zeh killed Zeh by MOD_ROCKET_SPLASH
+  0:06 Kill: 2 3 7: Isgalamido killed Zeh by MOD_ROCKET_SPLASH
+  0:07 ShutdownGame:
+`
+	tests := map[string]struct {
+		rules    ScoringRules
+		expected map[string]int
+	}{
+		"Default": {
+			rules:    DefaultScoringRules(),
+			expected: map[string]int{"Isgalamido": 2, "Zeh": 0},
+		},
+		"League": {
+			rules: ScoringRules{
+				Name:        "league",
+				Kill:        2,
+				Suicide:     -3,
+				WorldDeath:  -2,
+				WeaponBonus: map[string]int{"MOD_GAUNTLET": 5},
+				Streaks:     []StreakBonus{{Kills: 2, Bonus: 10}},
+			},
+			expected: map[string]int{"Isgalamido": 2 + 2 + 5 + 10 - 2 + 2, "Zeh": -3},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gs := newTestScanner(input, EPStrict)
+			gs.Scoring = test.rules
+			game, _, err := gs.GetGame()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if game.ScoringRules != test.rules.Name {
+				t.Errorf("Expected rules %s, but got %s", test.rules.Name, game.ScoringRules)
+			}
+			scores := make(map[string]int)
+			for _, pi := range game.PlayersInfoById {
+				scores[pi.Username] = pi.Score
+			}
+			if !reflect.DeepEqual(scores, test.expected) {
+				t.Errorf("Expected %v, but got %v", test.expected, scores)
+			}
+			if streak := game.PlayersInfoById[2].BestKillStreak; streak != 2 {
+				t.Errorf("Expected best kill streak 2, but got %d", streak)
+			}
+		})
+	}
+}
+
+func TestTeamKillScoringRule(t *testing.T) {
+	input := `  0:00 InitGame: \g_gametype\3\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\1
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\1
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Mocinha\t\2
+  0:02 Kill: 2 4 2: Isgalamido killed Mocinha by MOD_GAUNTLET
+  0:03 Kill: 2 3 2: Isgalamido killed Zeh by MOD_GAUNTLET
+  0:04 ShutdownGame:
+`
+	tests := map[string]struct {
+		teamKill int
+		expected int
+	}{
+		"Default": {teamKill: -1, expected: 1 + 5 - 1},
+		"Harsh":   {teamKill: -5, expected: 1 + 5 - 5},
+		"Ignored": {teamKill: 0, expected: 1 + 5},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gs := newTestScanner(input, EPStrict)
+			gs.Scoring.TeamKill = test.teamKill
+			// the weapon bonus is only given to kills of enemies
+			gs.Scoring.WeaponBonus = map[string]int{"MOD_GAUNTLET": 5}
+			game, _, err := gs.GetGame()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if score := game.PlayersInfoById[2].Score; score != test.expected {
+				t.Errorf("Expected %v, but got %v", test.expected, score)
+			}
+		})
+	}
+}
+
+func TestLoadScoringRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "league.json")
+	if err := os.WriteFile(path, []byte(`{"Suicide": -1, "WeaponBonus": {"MOD_GAUNTLET": 2}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadScoringRules(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := DefaultScoringRules()
+	expected.Name = "league"
+	expected.Suicide = -1
+	expected.WeaponBonus = map[string]int{"MOD_GAUNTLET": 2}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected %+v, but got %+v", expected, rules)
+	}
+}
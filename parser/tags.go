@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GameTag marks games that are usually left out of reports and aggregates.
+// Tags are bit flags, a game can have many.
+type GameTag uint8
+
+const (
+	// GTWarmup is a game on a server with g_doWarmup that ended without Exit
+	// before the warmup time, g_warmup, was over.
+	GTWarmup GameTag = 1 << iota
+	// GTFewHumans is a game with less human players than TagOptions.MinHumans.
+	GTFewHumans
+	GTNoKills
+	// GTBotOnly is a game where every player is a bot, or without players on a
+	// server that adds bots with bot_minplayers.
+	GTBotOnly
+	// GTShort is a game shorter than TagOptions.MinDuration.
+	GTShort
+)
+
+var allGameTags = []GameTag{GTWarmup, GTFewHumans, GTNoKills, GTBotOnly, GTShort}
+
+// defaultWarmup is the g_warmup of the server when it is not on the config.
+const defaultWarmup = 20 * time.Second
+
+type TagOptions struct {
+	MinHumans   int
+	MinDuration time.Duration
+}
+
+func DefaultTagOptions() TagOptions {
+	return TagOptions{
+		MinHumans:   2,
+		MinDuration: time.Minute,
+	}
+}
+
+// Any reports if t has any of the tags of other.
+func (t GameTag) Any(other GameTag) bool {
+	return t&other != 0
+}
+
+// Names returns the name of each tag of t.
+func (t GameTag) Names() []string {
+	names := []string{}
+	for _, tag := range allGameTags {
+		if t.Any(tag) {
+			names = append(names, tag.String())
+		}
+	}
+	return names
+}
+
+func (t GameTag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Names())
+}
+
+func (t *GameTag) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	tags, err := ParseGameTags(strings.Join(names, ","))
+	if err != nil {
+		return err
+	}
+	*t = tags
+	return nil
+}
+
+// ParseGameTags reads a comma separated list of tag names.
+func ParseGameTags(names string) (GameTag, error) {
+	var tags GameTag
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, tag := range allGameTags {
+			if tag.String() == name {
+				tags |= tag
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown game tag: %s", name)
+		}
+	}
+	return tags, nil
+}
+
+// Classify returns the tags of the game. Bots are the players with the skill
+// key on their userinfo.
+func (game *Game) Classify(opts TagOptions) GameTag {
+	var tags GameTag
+	humans, bots := 0, 0
+	countPlayer := func(pi *PlayersInfo) {
+		switch {
+		case pi.Id == 0 || pi.Id == UnknownPlayerId || pi.Username == "":
+		case pi.Bot:
+			bots++
+		default:
+			humans++
+		}
+	}
+	for _, pi := range game.PlayersInfoById {
+		countPlayer(pi)
+	}
+	for _, pi := range game.DisconnectedPlayers {
+		countPlayer(pi)
+	}
+
+	if humans < opts.MinHumans {
+		tags |= GTFewHumans
+	}
+	if game.TotalKills == 0 {
+		tags |= GTNoKills
+	}
+	if humans == 0 && (bots > 0 || game.limit("bot_minplayers") > 0) {
+		tags |= GTBotOnly
+	}
+
+	duration, known := game.Duration()
+	if known && duration < opts.MinDuration {
+		tags |= GTShort
+	}
+	if doWarmup, _ := strconv.Atoi(game.ServerConfig["g_doWarmup"]); doWarmup != 0 && known {
+		warmup := defaultWarmup
+		if seconds, err := strconv.Atoi(game.ServerConfig["g_warmup"]); err == nil {
+			warmup = time.Duration(seconds) * time.Second
+		}
+		condition := game.endingCondition()
+		if (condition == ECShutdown || condition == ECAbandoned) && duration <= warmup {
+			tags |= GTWarmup
+		}
+	}
+	return tags
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected GameTag
+	}{
+		"RegularGame": {
+			input: `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:30 Kill: 2 3 7: Isgalamido killed Zeh by MOD_ROCKET_SPLASH
+  5:00 Exit: Fraglimit hit.
+  5:00 ShutdownGame:
+`,
+			expected: 0,
+		},
+		"EmptyShortGame": {
+			input: `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:10 ShutdownGame:
+`,
+			expected: GTFewHumans | GTNoKills | GTShort,
+		},
+		"BotOnly": {
+			input: `  0:00 InitGame: \g_gametype\0\bot_minplayers\2\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Sarge\t\0\model\sarge\skill\ 2.00
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Doom\t\0\model\doom\skill\ 4.00
+  0:30 Kill: 2 3 7: Sarge killed Doom by MOD_ROCKET_SPLASH
+  5:00 Exit: Fraglimit hit.
+  5:00 ShutdownGame:
+`,
+			expected: GTFewHumans | GTBotOnly,
+		},
+		"EmptyBotServer": {
+			input: `  0:00 InitGame: \g_gametype\0\bot_minplayers\2\mapname\q3dm17
+  5:00 ShutdownGame:
+`,
+			expected: GTFewHumans | GTNoKills | GTBotOnly,
+		},
+		"Warmup": {
+			input: `  0:00 InitGame: \g_gametype\0\g_doWarmup\1\g_warmup\30\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:20 Kill: 2 3 7: Isgalamido killed Zeh by MOD_ROCKET_SPLASH
+  0:30 ShutdownGame:
+`,
+			expected: GTWarmup | GTShort,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			game, _, err := newTestScanner(test.input, EPStrict).GetGame()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if game.Tags != test.expected {
+				t.Errorf("Expected %v, but got %v", test.expected.Names(), game.Tags.Names())
+			}
+		})
+	}
+}
+
+func TestTagOptions(t *testing.T) {
+	input := `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:10 Kill: 1022 2 22: <world> killed Isgalamido by MOD_TRIGGER_HURT
+  0:40 ShutdownGame:
+`
+	gs := newTestScanner(input, EPStrict)
+	gs.TagOptions = TagOptions{MinHumans: 1, MinDuration: 30 * time.Second}
+	game, _, err := gs.GetGame()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if game.Tags != 0 {
+		t.Errorf("Expected no tags, but got %v", game.Tags.Names())
+	}
+}
+
+func TestParseGameTags(t *testing.T) {
+	tags, err := ParseGameTags("warmup, bot-only,short")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tags != GTWarmup|GTBotOnly|GTShort {
+		t.Errorf("Expected %v, but got %v", (GTWarmup | GTBotOnly | GTShort).Names(), tags.Names())
+	}
+	if _, err := ParseGameTags("warmup,bogus"); err == nil {
+		t.Errorf("Expected error for unknown tag")
+	}
+}
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Team is the t key of a userinfo, the team_t enum of Quake 3.
+type Team uint8
+
+const (
+	TMFree Team = iota
+	TMRed
+	TMBlue
+	TMSpectator
+)
+
+// Userinfo is the info string of a ClientUserinfoChanged line, such as
+// n\Isgalamido\t\0\model\xian/default\hc\100.
+type Userinfo struct {
+	// Name is the n key.
+	Name      string
+	Team      Team
+	Model     string
+	HeadModel string
+	// Handicap is the hc key, the max health of the player. It is 100 when
+	// the key is missing.
+	Handicap int
+	Color1   string
+	Color2   string
+	// Wins and Losses are the w and l keys, only used on tournaments.
+	Wins   int
+	Losses int
+	// TeamTask and TeamLeader are the tt and tl keys of team arena.
+	TeamTask   int
+	TeamLeader bool
+	// Skill is only set for bots, see Bot.
+	Skill float64
+	// Values has every key of the userinfo, including the ones above.
+	Values map[string]string
+}
+
+// Bot reports if the userinfo is of a bot. Only bots have the skill key.
+func (u Userinfo) Bot() bool {
+	_, ok := u.Values["skill"]
+	return ok
+}
+
+// parseUserinfo reads the info string of a ClientUserinfoChanged line. Only
+// the name is required. The team codes of the dialect are read besides the
+// team numbers.
+func parseUserinfo(info string, d *Dialect) (Userinfo, error) {
+	values := parseInfoString(info)
+	name, ok := values["n"]
+	if !ok {
+		return Userinfo{}, &SyntaxError{LHClientUserinfoChanged, "expecting n key on userinfo"}
+	}
+	userinfo := Userinfo{
+		Name:      name,
+		Model:     values["model"],
+		HeadModel: values["hmodel"],
+		Handicap:  100,
+		Color1:    values["c1"],
+		Color2:    values["c2"],
+		Values:    values,
+	}
+
+	if value, ok := values["t"]; ok {
+		team, err := d.team(value)
+		if err != nil {
+			return Userinfo{}, err
+		}
+		userinfo.Team = team
+	}
+
+	var teamLeader int
+	ints := []struct {
+		key   string
+		value *int
+	}{
+		{"hc", &userinfo.Handicap},
+		{"w", &userinfo.Wins},
+		{"l", &userinfo.Losses},
+		{"tt", &userinfo.TeamTask},
+		{"tl", &teamLeader},
+	}
+	for _, i := range ints {
+		value, ok := values[i.key]
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return Userinfo{}, &SyntaxError{LHClientUserinfoChanged, fmt.Sprintf("expecting %s to be an integer on userinfo", i.key)}
+		}
+		*i.value = n
+	}
+	userinfo.TeamLeader = teamLeader != 0
+
+	if skill, ok := values["skill"]; ok {
+		// the skill is padded, e.g. skill\ 2.00
+		s, err := strconv.ParseFloat(strings.TrimSpace(skill), 64)
+		if err != nil {
+			return Userinfo{}, &SyntaxError{LHClientUserinfoChanged, "expecting skill to be a number on userinfo"}
+		}
+		userinfo.Skill = s
+	}
+	return userinfo, nil
+}
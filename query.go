@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pedroegsilva/cw-test/batch"
+	"github.com/pedroegsilva/cw-test/parser"
+	"github.com/pedroegsilva/cw-test/reports"
+	"github.com/pedroegsilva/cw-test/store"
+	"github.com/rs/zerolog/log"
+)
+
+// runQuery prints the games of the stats store matching the filters. By
+// default their summaries are printed as json, -history prints the
+// performance of a player over time and -reports the game reports.
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	storeDir := fs.String("store", "", "directory of the stats store")
+	mapName := fs.String("map", "", "only games on this map")
+	player := fs.String("player", "", "only games with this player")
+	since := fs.String("since", "", "only games played from this date (YYYY-MM-DD or RFC3339)")
+	until := fs.String("until", "", "only games played before this date (YYYY-MM-DD or RFC3339)")
+	exclude := fs.String("exclude", "", "skip games with these tags, comma separated: warmup, few-humans, no-kills, bot-only and short")
+	last := fs.Duration("last", 0, "only games played on this last period, e.g. 720h")
+	history := fs.Bool("history", false, "print the kills, deaths and K/D of -player on each game")
+	printReports := fs.Bool("reports", false, "print the game reports, formatted by OUT_JSON or OUT_HUMAN")
+	readWhere := whereFlag(fs)
+	fs.Parse(args)
+
+	if *storeDir == "" || (*history && *player == "") {
+		fs.Usage()
+		return 2
+	}
+
+	filter := store.Filter{Map: *mapName, Player: *player}
+	var err error
+	if filter.Exclude, err = parser.ParseGameTags(*exclude); err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	if *since != "" {
+		if filter.Since, err = parseDate(*since); err != nil {
+			log.Error().Msg(err.Error())
+			return 2
+		}
+	}
+	if *until != "" {
+		if filter.Until, err = parseDate(*until); err != nil {
+			log.Error().Msg(err.Error())
+			return 2
+		}
+	}
+	if *last > 0 {
+		filter.Since = time.Now().Add(-*last)
+	}
+	where, err := readWhere()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+
+	statsStore, err := store.Open(*storeDir)
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("could not open store: %s", err))
+		return 2
+	}
+
+	// -where needs the whole games, they are only loaded when it is set
+	var games []*store.StoredGame
+	matched := make(map[string]bool)
+	if *printReports || where != nil {
+		for _, summary := range statsStore.Games(filter) {
+			stored, err := statsStore.Game(summary.Hash)
+			if err != nil {
+				log.Error().Msg(fmt.Sprintf("could not load game %s: %s", summary.Hash, err))
+				return 1
+			}
+			if where == nil || where.MatchGame(stored.Game) {
+				games = append(games, stored)
+				matched[summary.Hash] = true
+			}
+		}
+	}
+
+	if *printReports {
+		for _, stored := range games {
+			keep := batch.KeepPlayers(where, stored.Game)
+			if os.Getenv("OUT_HUMAN") != "" {
+				reports.PrintHumanReadableReport(stored.Game, stored.Hash, keep)
+			} else {
+				reports.PrintJson(stored.Game, stored.Hash, keep)
+			}
+		}
+		return 0
+	}
+
+	var result any
+	if *history {
+		points := statsStore.PlayerHistory(*player, filter)
+		if where != nil {
+			selected := []store.PlayerPoint{}
+			for _, point := range points {
+				if matched[point.Hash] {
+					selected = append(selected, point)
+				}
+			}
+			points = selected
+		}
+		result = points
+	} else {
+		summaries := statsStore.Games(filter)
+		if where != nil {
+			selected := []store.GameSummary{}
+			for _, summary := range summaries {
+				if matched[summary.Hash] {
+					selected = append(selected, summary)
+				}
+			}
+			summaries = selected
+		}
+		result = summaries
+	}
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("could not format json: %s", err))
+		return 1
+	}
+	fmt.Println(string(jsonData))
+	return 0
+}
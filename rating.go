@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/pedroegsilva/cw-test/batch"
+	"github.com/pedroegsilva/cw-test/parser"
+	"github.com/pedroegsilva/cw-test/rating"
+	"github.com/rs/zerolog/log"
+)
+
+// runRating updates the skill ratings saved on -ratings with the games of the
+// logs, in order, and prints the leaderboard of -scope.
+func runRating(args []string) int {
+	fs := flag.NewFlagSet("rating", flag.ExitOnError)
+	var inputPaths pathList
+	fs.Var(&inputPaths, "i", "full path of the log file, can be repeated. Extra arguments are also read as log files")
+	ratingsPath := fs.String("ratings", "", "json file where the ratings are kept between runs")
+	source := fs.String("source", "placement", "outcome compared between players: placement (final score) or kills (kills between each pair)")
+	scope := fs.String("scope", "global", "leaderboard printed: global, map:<mapname> or gametype:<g_gametype>")
+	mode := fs.String("mode", "lenient", "error policy: strict, lenient or recover")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of log files parsed concurrently")
+	readTagFlags := tagFlags(fs)
+	readScoring := scoringFlag(fs)
+	readDialect := dialectFlag(fs)
+	readWhere := whereFlag(fs)
+	fs.Parse(args)
+	inputPaths = append(inputPaths, fs.Args()...)
+
+	if *ratingsPath == "" && len(inputPaths) == 0 {
+		fs.Usage()
+		return 2
+	}
+	policy, err := parser.ParseErrorPolicy(*mode)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	tagOptions, exclude, err := readTagFlags()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	scoring, err := readScoring()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	dialect, err := readDialect()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	where, err := readWhere()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	config := rating.DefaultConfig()
+	if config.Source, err = rating.ParseSource(*source); err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+
+	system := rating.NewSystem(config)
+	if *ratingsPath != "" {
+		if system, err = rating.Load(*ratingsPath, config); err != nil {
+			log.Error().Msg(fmt.Sprintf("could not load ratings: %s", err))
+			return 2
+		}
+	}
+
+	code := 0
+	opts := batch.Options{Workers: *workers, ErrorPolicy: policy, TagOptions: tagOptions, Scoring: scoring, Exclude: exclude, Dialect: dialect}
+	batch.ProcessFiles(inputPaths, opts, func(result *batch.FileResult) {
+		for idx, gr := range result.Games {
+			if gr.Err != nil {
+				log.Error().Msg(fmt.Sprintf("error on %s: %s", gameName(result.Path, idx+1, len(inputPaths) > 1), gr.Err))
+				continue
+			}
+			// every player of the games matched is rated
+			if !gr.Game.Tags.Any(exclude) && (where == nil || where.MatchGame(gr.Game)) {
+				system.AddGame(gr.Game)
+			}
+		}
+		if result.Err != nil {
+			log.Error().Msg(fmt.Sprintf("aborting %s: %s", result.Path, result.Err))
+			code = 1
+		}
+	})
+
+	if *ratingsPath != "" {
+		if err := system.Save(*ratingsPath); err != nil {
+			log.Error().Msg(fmt.Sprintf("could not save ratings: %s", err))
+			return 1
+		}
+	}
+
+	leaderboard := system.Leaderboard(*scope)
+	if os.Getenv("OUT_HUMAN") != "" {
+		fmt.Printf("%s leaderboard:\n", *scope)
+		for i, r := range leaderboard {
+			fmt.Printf("  %d. %s: %.0f ± %.0f (%d games)\n", i+1, r.Name, r.Rating, 2*r.Deviation, r.Games)
+		}
+		return code
+	}
+	jsonData, err := json.MarshalIndent(leaderboard, "", "  ")
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("could not format json: %s", err))
+		return 1
+	}
+	fmt.Println(string(jsonData))
+	return code
+}
@@ -0,0 +1,416 @@
+package rating
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+// glickoScale converts between the Glicko and the Glicko-2 scales.
+const glickoScale = 173.7178
+
+// Source is the game outcome used to compare two players.
+type Source uint8
+
+const (
+	// SPlacement compares the final score of the players.
+	SPlacement Source = iota
+	// SKills compares how many times each player killed the other.
+	SKills
+)
+
+func ParseSource(source string) (Source, error) {
+	switch source {
+	case "placement", "":
+		return SPlacement, nil
+	case "kills":
+		return SKills, nil
+	}
+	return SPlacement, fmt.Errorf("unknown rating source: %s", source)
+}
+
+func (s Source) String() string {
+	if s == SKills {
+		return "kills"
+	}
+	return "placement"
+}
+
+func (s Source) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Source) UnmarshalText(text []byte) error {
+	source, err := ParseSource(string(text))
+	*s = source
+	return err
+}
+
+// Rating is the Glicko-2 rating of a player on a scope. Deviation is the
+// uncertainty of the rating, it shrinks as the player plays and grows back
+// while the player is inactive.
+type Rating struct {
+	Name       string
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+	Games      int
+	// LastPeriod is the period of the scope when the player last played.
+	LastPeriod int
+}
+
+// Conservative is the rating the player has with high confidence, used to
+// seed tournaments without favouring players with few games.
+func (r *Rating) Conservative() float64 {
+	return r.Rating - 2*r.Deviation
+}
+
+type Config struct {
+	Source            Source
+	InitialRating     float64
+	InitialDeviation  float64
+	InitialVolatility float64
+	// Tau constrains the change of volatility over time.
+	Tau float64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Source:            SPlacement,
+		InitialRating:     1500,
+		InitialDeviation:  350,
+		InitialVolatility: 0.06,
+		Tau:               0.5,
+	}
+}
+
+// scopeRatings holds the ratings of a scope, Period counts its games. Each
+// game is a rating period of Glicko-2.
+type scopeRatings struct {
+	Period  int
+	Players map[string]*Rating
+}
+
+// System keeps the ratings of the players on the global scope and on a scope
+// per map and per gametype, updated game by game. Applied holds the content
+// hash of the games already rated, so a game is only rated once.
+type System struct {
+	Config  Config
+	Scopes  map[string]*scopeRatings
+	Applied map[string]bool
+}
+
+func NewSystem(config Config) *System {
+	return &System{
+		Config:  config,
+		Scopes:  make(map[string]*scopeRatings),
+		Applied: make(map[string]bool),
+	}
+}
+
+// Scopes of a game: the global scope, its map and its gametype.
+func gameScopes(game *parser.Game) []string {
+	scopes := []string{"global"}
+	if m := game.ServerConfig["mapname"]; m != "" {
+		scopes = append(scopes, "map:"+m)
+	}
+	if gt := game.ServerConfig["g_gametype"]; gt != "" {
+		scopes = append(scopes, "gametype:"+gt)
+	}
+	return scopes
+}
+
+type player struct {
+	name  string
+	score int
+	kills map[string]int
+}
+
+// AddGame updates the ratings of the players of the game, including the ones
+// that disconnected before the end. Games with less than two players do not
+// change any rating. It returns false when the game was already rated.
+func (s *System) AddGame(game *parser.Game) bool {
+	hash := game.Hash()
+	if s.Applied[hash] {
+		return false
+	}
+	s.Applied[hash] = true
+
+	// a player that reconnects has an entry per connection
+	byName := make(map[string]*player)
+	add := func(pi *parser.PlayersInfo) {
+		if pi.Id == 0 || pi.Id == parser.UnknownPlayerId || pi.Username == "" {
+			return
+		}
+		p, ok := byName[pi.Username]
+		if !ok {
+			p = &player{name: pi.Username, kills: make(map[string]int)}
+			byName[pi.Username] = p
+		}
+		p.score += pi.Score
+		for victim, count := range pi.KillCountByPlayerTag {
+			p.kills[victim] += count
+		}
+	}
+	for _, pi := range game.PlayersInfoById {
+		add(pi)
+	}
+	for _, pi := range game.DisconnectedPlayers {
+		add(pi)
+	}
+	if len(byName) < 2 {
+		return true
+	}
+	players := make([]player, 0, len(byName))
+	for _, p := range byName {
+		players = append(players, *p)
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].name < players[j].name })
+
+	for _, scope := range gameScopes(game) {
+		s.updateScope(scope, players)
+	}
+	return true
+}
+
+type outcome struct {
+	opponent *Rating
+	score    float64
+}
+
+func (s *System) updateScope(scope string, players []player) {
+	sr, ok := s.Scopes[scope]
+	if !ok {
+		sr = &scopeRatings{Players: make(map[string]*Rating)}
+		s.Scopes[scope] = sr
+	}
+	sr.Period++
+
+	// every update uses the ratings from before the game
+	before := make(map[string]Rating, len(players))
+	for _, p := range players {
+		r := s.rating(sr, p.name)
+		s.decay(r, sr.Period)
+		before[p.name] = *r
+	}
+
+	for i, p := range players {
+		var outcomes []outcome
+		for j, o := range players {
+			if i == j {
+				continue
+			}
+			score, ok := s.pairScore(p, o)
+			if !ok {
+				continue
+			}
+			opponent := before[o.name]
+			outcomes = append(outcomes, outcome{opponent: &opponent, score: score})
+		}
+		r := sr.Players[p.name]
+		if len(outcomes) > 0 {
+			s.update(r, before[p.name], outcomes)
+		}
+		r.Games++
+		r.LastPeriod = sr.Period
+	}
+}
+
+// pairScore is the result of p against o, 1 for a win, 0.5 for a draw and 0
+// for a loss. It is false when the source has no result for the pair.
+func (s *System) pairScore(p player, o player) (float64, bool) {
+	switch s.Config.Source {
+	case SKills:
+		won, lost := p.kills[o.name], o.kills[p.name]
+		if won+lost == 0 {
+			return 0, false
+		}
+		return float64(won) / float64(won+lost), true
+	default:
+		switch {
+		case p.score > o.score:
+			return 1, true
+		case p.score < o.score:
+			return 0, true
+		}
+		return 0.5, true
+	}
+}
+
+func (s *System) rating(sr *scopeRatings, name string) *Rating {
+	r, ok := sr.Players[name]
+	if !ok {
+		r = &Rating{
+			Name:       name,
+			Rating:     s.Config.InitialRating,
+			Deviation:  s.Config.InitialDeviation,
+			Volatility: s.Config.InitialVolatility,
+			LastPeriod: sr.Period - 1,
+		}
+		sr.Players[name] = r
+	}
+	return r
+}
+
+// decay grows the deviation of a player for each period it did not play, as
+// Glicko-2 does for players without games on a rating period.
+func (s *System) decay(r *Rating, period int) {
+	missed := period - r.LastPeriod - 1
+	if missed <= 0 {
+		return
+	}
+	phi := r.Deviation / glickoScale
+	phi = math.Sqrt(phi*phi + float64(missed)*r.Volatility*r.Volatility)
+	r.Deviation = math.Min(phi*glickoScale, s.Config.InitialDeviation)
+}
+
+// update applies the Glicko-2 rating period update to r.
+func (s *System) update(r *Rating, before Rating, outcomes []outcome) {
+	mu := (before.Rating - s.Config.InitialRating) / glickoScale
+	phi := before.Deviation / glickoScale
+	sigma := before.Volatility
+
+	var vInv, deltaSum float64
+	for _, o := range outcomes {
+		muJ := (o.opponent.Rating - s.Config.InitialRating) / glickoScale
+		gJ := g(o.opponent.Deviation / glickoScale)
+		e := 1 / (1 + math.Exp(-gJ*(mu-muJ)))
+		vInv += gJ * gJ * e * (1 - e)
+		deltaSum += gJ * (o.score - e)
+	}
+	v := 1 / vInv
+	delta := v * deltaSum
+
+	sigma = s.volatility(phi, sigma, v, delta)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu = mu + phi*phi*deltaSum
+
+	r.Rating = mu*glickoScale + s.Config.InitialRating
+	r.Deviation = phi * glickoScale
+	r.Volatility = sigma
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// volatility finds the new volatility with the Illinois algorithm, as on the
+// Glicko-2 paper.
+func (s *System) volatility(phi float64, sigma float64, v float64, delta float64) float64 {
+	const epsilon = 0.000001
+	tau := s.Config.Tau
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA = fA / 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
+
+// Leaderboard returns the ratings of a scope ordered by the conservative
+// rating. The deviation of each player includes the decay for the periods
+// missed until the last game of the scope.
+func (s *System) Leaderboard(scope string) []Rating {
+	sr, ok := s.Scopes[scope]
+	if !ok {
+		return nil
+	}
+	board := make([]Rating, 0, len(sr.Players))
+	for _, r := range sr.Players {
+		current := *r
+		s.decay(&current, sr.Period+1)
+		board = append(board, current)
+	}
+	sort.Slice(board, func(i, j int) bool {
+		if board[i].Conservative() != board[j].Conservative() {
+			return board[i].Conservative() > board[j].Conservative()
+		}
+		return board[i].Name < board[j].Name
+	})
+	return board
+}
+
+// ratingsFile is the saved form of a System. Games holds the hashes of the
+// rated games, sorted to keep the file stable.
+type ratingsFile struct {
+	Source Source
+	Games  []string
+	Scopes map[string]*scopeRatings
+}
+
+// Load reads the ratings saved on path. A missing file results on an empty
+// system with the given config. Ratings built with another source can not be
+// updated, as the ratings of both sources are not comparable.
+func Load(path string, config Config) (*System, error) {
+	s := NewSystem(config)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file ratingsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if file.Scopes == nil {
+		return nil, fmt.Errorf("%s is not a ratings file", path)
+	}
+	if file.Source != config.Source {
+		return nil, fmt.Errorf("ratings built with source %s, not %s", file.Source, config.Source)
+	}
+	s.Scopes = file.Scopes
+	for _, hash := range file.Games {
+		s.Applied[hash] = true
+	}
+	return s, nil
+}
+
+func (s *System) Save(path string) error {
+	file := ratingsFile{Source: s.Config.Source, Scopes: s.Scopes}
+	for hash := range s.Applied {
+		file.Games = append(file.Games, hash)
+	}
+	sort.Strings(file.Games)
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,203 @@
+package rating
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+type testPlayer struct {
+	name  string
+	score int
+	kills map[string]int
+}
+
+// gameCount gives each test game a start time, so games with the same players
+// have different hashes.
+var gameCount int
+
+func newGame(mapName string, players ...testPlayer) *parser.Game {
+	gameCount++
+	game := &parser.Game{
+		PlayersInfoById: make(map[int]*parser.PlayersInfo),
+		ServerConfig:    map[string]string{"mapname": mapName, "g_gametype": "0"},
+		StartTime:       fmt.Sprintf("%d:00", gameCount),
+	}
+	for i, p := range players {
+		kills := p.kills
+		if kills == nil {
+			kills = make(map[string]int)
+		}
+		game.PlayersInfoById[i+2] = &parser.PlayersInfo{Id: i + 2, Username: p.name, Score: p.score, KillCountByPlayerTag: kills}
+	}
+	return game
+}
+
+func TestPlacement(t *testing.T) {
+	s := NewSystem(DefaultConfig())
+	for i := 0; i < 5; i++ {
+		s.AddGame(newGame("q3dm17", testPlayer{name: "Zeh", score: 10}, testPlayer{name: "Mal", score: 2}, testPlayer{name: "Chessus", score: 5}))
+	}
+
+	board := s.Leaderboard("global")
+	names := []string{"Zeh", "Chessus", "Mal"}
+	if len(board) != len(names) {
+		t.Fatalf("Expected %d players, but got %d", len(names), len(board))
+	}
+	for i, name := range names {
+		if board[i].Name != name {
+			t.Errorf("Expected %s on position %d, but got %s", name, i+1, board[i].Name)
+		}
+		if board[i].Games != 5 {
+			t.Errorf("Expected 5 games for %s, but got %d", name, board[i].Games)
+		}
+		if board[i].Deviation >= 350 {
+			t.Errorf("Expected the deviation of %s to shrink, but got %f", name, board[i].Deviation)
+		}
+	}
+	if board[0].Rating <= 1500 || board[2].Rating >= 1500 {
+		t.Errorf("Expected Zeh above 1500 and Mal below, but got %f and %f", board[0].Rating, board[2].Rating)
+	}
+
+	if len(s.Leaderboard("map:q3dm17")) != 3 || len(s.Leaderboard("gametype:0")) != 3 {
+		t.Errorf("Expected the map and gametype scopes to have the 3 players")
+	}
+	if s.Leaderboard("map:q3dm6") != nil {
+		t.Errorf("Expected no leaderboard for a map without games")
+	}
+}
+
+func TestKills(t *testing.T) {
+	config := DefaultConfig()
+	config.Source = SKills
+	s := NewSystem(config)
+	// Mal has the higher score, but Zeh wins every duel between them
+	s.AddGame(newGame("q3dm17",
+		testPlayer{name: "Zeh", score: 1, kills: map[string]int{"Mal": 3}},
+		testPlayer{name: "Mal", score: 5, kills: map[string]int{"Zeh": 1}},
+		testPlayer{name: "Chessus", score: 0},
+	))
+
+	board := s.Leaderboard("global")
+	if board[0].Name != "Zeh" {
+		t.Errorf("Expected Zeh on top, but got %s", board[0].Name)
+	}
+	for _, r := range board {
+		if r.Name == "Chessus" && (r.Rating != 1500 || r.Deviation != 350) {
+			t.Errorf("Expected Chessus without duels to keep the initial rating, but got %f ± %f", r.Rating, r.Deviation)
+		}
+	}
+}
+
+func TestInactivityDecay(t *testing.T) {
+	s := NewSystem(DefaultConfig())
+	s.AddGame(newGame("q3dm17", testPlayer{name: "Zeh", score: 3}, testPlayer{name: "Mal", score: 1}))
+	s.AddGame(newGame("q3dm17", testPlayer{name: "Zeh", score: 3}, testPlayer{name: "Mal", score: 1}))
+	deviation := s.Scopes["global"].Players["Mal"].Deviation
+
+	for i := 0; i < 20; i++ {
+		s.AddGame(newGame("q3dm17", testPlayer{name: "Zeh", score: 3}, testPlayer{name: "Isgalamido", score: 1}))
+	}
+	for _, r := range s.Leaderboard("global") {
+		if r.Name == "Mal" && r.Deviation <= deviation {
+			t.Errorf("Expected the deviation of an inactive player to grow from %f, but got %f", deviation, r.Deviation)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratings.json")
+	s, err := Load(path, DefaultConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	s.AddGame(newGame("q3dm17", testPlayer{name: "Zeh", score: 3}, testPlayer{name: "Mal", score: 1}))
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	loaded, err := Load(path, DefaultConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected, got := s.Leaderboard("global"), loaded.Leaderboard("global")
+	if len(expected) != len(got) {
+		t.Fatalf("Expected %d players, but got %d", len(expected), len(got))
+	}
+	for i := range expected {
+		if expected[i] != got[i] {
+			t.Errorf("Expected %v, but got %v", expected[i], got[i])
+		}
+	}
+}
+
+func TestAddGameOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratings.json")
+	game := newGame("q3dm17", testPlayer{name: "Zeh", score: 3}, testPlayer{name: "Mal", score: 1})
+	s := NewSystem(DefaultConfig())
+	if !s.AddGame(game) {
+		t.Errorf("Expected the game to be rated")
+	}
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	loaded, err := Load(path, DefaultConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if loaded.AddGame(game) {
+		t.Errorf("Expected the game to be rated only once")
+	}
+	expected, got := s.Leaderboard("global"), loaded.Leaderboard("global")
+	for i := range expected {
+		if expected[i] != got[i] {
+			t.Errorf("Expected %v, but got %v", expected[i], got[i])
+		}
+	}
+}
+
+func TestLoadOtherSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratings.json")
+	config := DefaultConfig()
+	config.Source = SKills
+	if err := NewSystem(config).Save(path); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := Load(path, DefaultConfig()); err == nil {
+		t.Errorf("Expected an error loading kills ratings with the placement source")
+	}
+	if _, err := Load(path, config); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// a file without the scopes is not a ratings file
+	if err := os.WriteFile(path, []byte(`{"global":{"Period":1,"Players":{}}}`), 0o644); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := Load(path, config); err == nil {
+		t.Errorf("Expected an error loading a file without scopes")
+	}
+}
+
+func TestDisconnectedPlayers(t *testing.T) {
+	s := NewSystem(DefaultConfig())
+	game := newGame("q3dm17", testPlayer{name: "Zeh", score: 3})
+	// Mal left the game losing, reconnected and left again
+	game.DisconnectedPlayers = []*parser.PlayersInfo{
+		{Id: 3, Username: "Mal", Score: -1},
+		{Id: 4, Username: "Mal", Score: 1},
+	}
+	s.AddGame(game)
+
+	board := s.Leaderboard("global")
+	if len(board) != 2 {
+		t.Fatalf("Expected 2 players, but got %d", len(board))
+	}
+	if board[1].Name != "Mal" || board[1].Rating >= 1500 || board[1].Games != 1 {
+		t.Errorf("Expected Mal to take the loss on a single game, but got %+v", board[1])
+	}
+}
@@ -0,0 +1,317 @@
+package reports
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Change is a field that differs between the two sides of a diff.
+type Change struct {
+	Field string
+	A     any
+	B     any
+}
+
+// PlayerDiff has the changes of a player, OnlyIn is "a" or "b" when the player
+// is only on one side.
+type PlayerDiff struct {
+	Name    string
+	OnlyIn  string   `json:",omitempty"`
+	Changes []Change `json:",omitempty"`
+}
+
+// GameDiff has the changes of a game. GameA and GameB are the identifiers of
+// the game on each side, only one of them is set when the game is only on one
+// side.
+type GameDiff struct {
+	Map       string
+	StartTime string
+	GameA     string `json:",omitempty"`
+	GameB     string `json:",omitempty"`
+	// MatchedBy is how the games were paired: hash, time, order or name.
+	MatchedBy string        `json:",omitempty"`
+	OnlyIn    string        `json:",omitempty"`
+	Changes   []Change      `json:",omitempty"`
+	Players   []*PlayerDiff `json:",omitempty"`
+}
+
+// ReportDiff lists the games that differ between two sets of reports.
+type ReportDiff struct {
+	Games []*GameDiff
+	// Same is the amount of paired games without differences.
+	Same int
+}
+
+// Empty tells if both sides have the same games.
+func (d *ReportDiff) Empty() bool {
+	return len(d.Games) == 0
+}
+
+// ReadReports reads the reports printed by PrintJson, one after the other.
+// Other objects, such as the summary printed after the reports, are skipped.
+func ReadReports(r io.Reader) ([]*Report, error) {
+	var reps []*Report
+	decoder := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			return reps, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, err
+		}
+		if _, ok := fields["GameIdentifier"]; !ok {
+			continue
+		}
+		var report Report
+		if err := json.Unmarshal(raw, &report); err != nil {
+			return nil, err
+		}
+		reps = append(reps, &report)
+	}
+}
+
+// DiffReports pairs the games of a and b and compares them. Since the
+// positional names of the games change when a log gains or loses games, the
+// games are paired by a stable identity, each method on the games left by the
+// previous one. The content hash pairs the same game read by the same parser.
+// The source, map and server start time pair the games after a parser change,
+// which changes the hash; the server time restarts on every game, so only the
+// start times unique on their source and map are used. The order among the
+// games of the same map pairs the logs of servers with their own clocks. At
+// last, when a side has reports without the identity, the games are paired by
+// their names.
+func DiffReports(a, b []*Report) *ReportDiff {
+	pairs := make([]int, len(a))
+	for i := range pairs {
+		pairs[i] = -1
+	}
+	matchedBy := make([]string, len(a))
+	paired := make([]bool, len(b))
+
+	// pair pairs the games still unpaired that have the same key, games
+	// without a key are left for the next method
+	pair := func(method string, keyA, keyB func(int) string) {
+		byKey := make(map[string][]int)
+		for j := range b {
+			if !paired[j] {
+				if k := keyB(j); k != "" {
+					byKey[k] = append(byKey[k], j)
+				}
+			}
+		}
+		for i := range a {
+			if pairs[i] >= 0 {
+				continue
+			}
+			k := keyA(i)
+			if k == "" || len(byKey[k]) == 0 {
+				continue
+			}
+			j := byKey[k][0]
+			byKey[k] = byKey[k][1:]
+			pairs[i], matchedBy[i], paired[j] = j, method, true
+		}
+	}
+
+	hash := func(reps []*Report) func(int) string {
+		return func(i int) string { return reps[i].GameHash }
+	}
+	pair("hash", hash(a), hash(b))
+	startTime := func(reps []*Report) func(int) string {
+		keys := make([]string, len(reps))
+		count := make(map[string]int)
+		for i, report := range reps {
+			if report.StartTime != "" {
+				keys[i] = report.Source + " " + report.Map + " " + report.StartTime
+				count[keys[i]]++
+			}
+		}
+		return func(i int) string {
+			if count[keys[i]] > 1 {
+				return ""
+			}
+			return keys[i]
+		}
+	}
+	pair("time", startTime(a), startTime(b))
+	// the order is counted among the games still unpaired, so a game that is
+	// only on one side does not shift the others
+	orderA := mapOrder(a, func(i int) bool { return pairs[i] < 0 })
+	orderB := mapOrder(b, func(j int) bool { return !paired[j] })
+	pair("order", func(i int) string { return orderA[i] }, func(j int) string { return orderB[j] })
+	// the reports printed before the identity was added only have their names
+	if !hasIdentity(a) || !hasIdentity(b) {
+		name := func(reps []*Report) func(int) string {
+			return func(i int) string { return reps[i].GameIdentifier }
+		}
+		pair("name", name(a), name(b))
+	}
+
+	diff := &ReportDiff{Games: []*GameDiff{}}
+	for i, ra := range a {
+		if pairs[i] < 0 {
+			diff.Games = append(diff.Games, &GameDiff{Map: ra.Map, StartTime: ra.StartTime, GameA: ra.GameIdentifier, OnlyIn: "a"})
+			continue
+		}
+		gd := diffGame(ra, b[pairs[i]])
+		if len(gd.Changes) == 0 && len(gd.Players) == 0 {
+			diff.Same++
+			continue
+		}
+		gd.MatchedBy = matchedBy[i]
+		diff.Games = append(diff.Games, gd)
+	}
+	for j, rb := range b {
+		if !paired[j] {
+			diff.Games = append(diff.Games, &GameDiff{Map: rb.Map, StartTime: rb.StartTime, GameB: rb.GameIdentifier, OnlyIn: "b"})
+		}
+	}
+	return diff
+}
+
+func hasIdentity(reps []*Report) bool {
+	for _, report := range reps {
+		if report.GameHash == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// mapOrder keys the selected reports by their map and their position among
+// the selected reports of the same map.
+func mapOrder(reps []*Report, selected func(int) bool) map[int]string {
+	keys := make(map[int]string)
+	count := make(map[string]int)
+	for i, report := range reps {
+		if !selected(i) {
+			continue
+		}
+		count[report.Map]++
+		keys[i] = fmt.Sprintf("%s #%d", report.Map, count[report.Map])
+	}
+	return keys
+}
+
+func diffGame(a, b *Report) *GameDiff {
+	gd := &GameDiff{Map: a.Map, StartTime: a.StartTime, GameA: a.GameIdentifier, GameB: b.GameIdentifier}
+	gd.Changes = appendChange(gd.Changes, "TotalKills", a.TotalKills, b.TotalKills)
+	gd.Changes = appendChange(gd.Changes, "TotalTeamKills", a.TotalTeamKills, b.TotalTeamKills)
+	gd.Changes = appendChange(gd.Changes, "EndingReason", a.EndingReason, b.EndingReason)
+	gd.Changes = appendChange(gd.Changes, "EndingCondition", a.EndingCondition, b.EndingCondition)
+	gd.Changes = appendChange(gd.Changes, "Winner", a.Winner, b.Winner)
+	gd.Changes = appendChange(gd.Changes, "WinningTeam", a.WinningTeam, b.WinningTeam)
+
+	playersA, playersB := playersByName(a), playersByName(b)
+	names := make([]string, 0, len(playersA)+len(playersB))
+	for name := range playersA {
+		names = append(names, name)
+	}
+	for name := range playersB {
+		if _, ok := playersA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pa, inA := playersA[name]
+		pb, inB := playersB[name]
+		switch {
+		case !inB:
+			gd.Players = append(gd.Players, &PlayerDiff{Name: name, OnlyIn: "a"})
+		case !inA:
+			gd.Players = append(gd.Players, &PlayerDiff{Name: name, OnlyIn: "b"})
+		default:
+			var changes []Change
+			changes = appendChange(changes, "Placement", pa.Placement, pb.Placement)
+			changes = appendChange(changes, "Score", pa.Score, pb.Score)
+			changes = appendChange(changes, "KillCount", pa.KillCount, pb.KillCount)
+			changes = appendChange(changes, "TeamKillCount", pa.TeamKillCount, pb.TeamKillCount)
+			if len(changes) > 0 {
+				gd.Players = append(gd.Players, &PlayerDiff{Name: name, Changes: changes})
+			}
+		}
+	}
+	return gd
+}
+
+func appendChange[T comparable](changes []Change, field string, a, b T) []Change {
+	if a == b {
+		return changes
+	}
+	return append(changes, Change{Field: field, A: a, B: b})
+}
+
+// playersByName merges the rows of a player that reconnected, which have the
+// same placement, summing their score and kills.
+func playersByName(report *Report) map[string]*PlayerStatistics {
+	players := make(map[string]*PlayerStatistics, len(report.PlayersStatistics))
+	for _, ps := range report.PlayersStatistics {
+		merged, ok := players[ps.Name]
+		if !ok {
+			players[ps.Name] = &PlayerStatistics{Name: ps.Name, Placement: ps.Placement, Score: ps.Score, KillCount: ps.KillCount, TeamKillCount: ps.TeamKillCount}
+			continue
+		}
+		merged.Score += ps.Score
+		merged.KillCount += ps.KillCount
+		merged.TeamKillCount += ps.TeamKillCount
+	}
+	return players
+}
+
+func PrintHumanReadableDiff(diff *ReportDiff) {
+	fmt.Println("-------------------- diff --------------------")
+	for _, gd := range diff.Games {
+		switch gd.OnlyIn {
+		case "a":
+			fmt.Printf("only on a: %s%s\n", gd.GameA, identity(gd))
+			continue
+		case "b":
+			fmt.Printf("only on b: %s%s\n", gd.GameB, identity(gd))
+			continue
+		}
+		fmt.Printf("%s -> %s%s, paired by %s:\n", gd.GameA, gd.GameB, identity(gd), gd.MatchedBy)
+		for _, c := range gd.Changes {
+			fmt.Printf("  %s: %v -> %v\n", c.Field, c.A, c.B)
+		}
+		for _, pd := range gd.Players {
+			if pd.OnlyIn != "" {
+				fmt.Printf("  %s: only on %s\n", pd.Name, pd.OnlyIn)
+				continue
+			}
+			fmt.Printf("  %s:\n", pd.Name)
+			for _, c := range pd.Changes {
+				fmt.Printf("    %s: %v -> %v\n", c.Field, c.A, c.B)
+			}
+		}
+	}
+	fmt.Println("Same games:", diff.Same)
+}
+
+// identity is the map and the start time of the game, when the reports have them.
+func identity(gd *GameDiff) string {
+	if gd.Map == "" && gd.StartTime == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s %s)", gd.Map, gd.StartTime)
+}
+
+func PrintDiffJson(diff *ReportDiff) {
+	jsonData, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("could not format json diff. err: %s", err))
+		return
+	}
+	fmt.Println(string(jsonData))
+}
@@ -0,0 +1,144 @@
+package reports
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDiffReports(t *testing.T) {
+	game := func(name, hash, mapName, start string, kills int, players ...*PlayerStatistics) *Report {
+		return &Report{GameIdentifier: name, GameHash: hash, Map: mapName, StartTime: start, TotalKills: kills, EndingReason: "ShutdownGame", PlayersStatistics: players}
+	}
+	sourced := func(report *Report, source string) *Report {
+		report.Source = source
+		return report
+	}
+	player := func(name string, score int) *PlayerStatistics {
+		return &PlayerStatistics{Name: name, Score: score, KillCount: score}
+	}
+
+	tests := map[string]struct {
+		a, b     []*Report
+		expected []*GameDiff
+		same     int
+	}{
+		"same games with other names": {
+			a:    []*Report{game("game-1", "h1", "q3dm17", "0:00", 3), game("game-2", "h2", "q3dm17", "5:00", 1)},
+			b:    []*Report{game("game-3", "h1", "q3dm17", "0:00", 3), game("game-4", "h2", "q3dm17", "5:00", 1)},
+			same: 2,
+		},
+		"paired by time after a parser change": {
+			a: []*Report{game("game-1", "h1", "q3dm17", "0:00", 3, player("Zeh", 3))},
+			b: []*Report{game("game-1", "h2", "q3dm17", "0:00", 2, player("Zeh", 2), player("Dono", 0))},
+			expected: []*GameDiff{{
+				Map: "q3dm17", StartTime: "0:00", GameA: "game-1", GameB: "game-1", MatchedBy: "time",
+				Changes: []Change{{Field: "TotalKills", A: 3, B: 2}},
+				Players: []*PlayerDiff{
+					{Name: "Dono", OnlyIn: "b"},
+					{Name: "Zeh", Changes: []Change{{Field: "Score", A: 3, B: 2}, {Field: "KillCount", A: 3, B: 2}}},
+				},
+			}},
+		},
+		"paired by order on other servers": {
+			a: []*Report{game("game-1", "h1", "q3dm17", "1:00", 3), game("game-2", "h2", "q3dm6", "9:00", 1)},
+			b: []*Report{game("game-1", "h3", "q3dm6", "4:00", 1), game("game-2", "h4", "q3dm17", "2:00", 4)},
+			expected: []*GameDiff{{
+				Map: "q3dm17", StartTime: "1:00", GameA: "game-1", GameB: "game-2", MatchedBy: "order",
+				Changes: []Change{{Field: "TotalKills", A: 3, B: 4}},
+			}},
+			same: 1,
+		},
+		"same start time on other sources": {
+			a: []*Report{sourced(game("game-1", "h1", "q3dm17", "0:00", 3), "1"), sourced(game("game-2", "h2", "q3dm17", "0:00", 1), "2")},
+			b: []*Report{sourced(game("game-1", "h3", "q3dm17", "0:00", 1), "2"), sourced(game("game-2", "h4", "q3dm17", "0:00", 4), "1")},
+			expected: []*GameDiff{
+				{
+					Map: "q3dm17", StartTime: "0:00", GameA: "game-1", GameB: "game-2", MatchedBy: "time",
+					Changes: []Change{{Field: "TotalKills", A: 3, B: 4}},
+				},
+			},
+			same: 1,
+		},
+		"start time repeated on the source": {
+			a: []*Report{game("game-1", "h1", "q3dm17", "0:00", 3), game("game-2", "h2", "q3dm17", "0:00", 1)},
+			b: []*Report{game("game-1", "h3", "q3dm17", "0:00", 2), game("game-2", "h4", "q3dm17", "0:00", 1)},
+			expected: []*GameDiff{{
+				Map: "q3dm17", StartTime: "0:00", GameA: "game-1", GameB: "game-1", MatchedBy: "order",
+				Changes: []Change{{Field: "TotalKills", A: 3, B: 2}},
+			}},
+			same: 1,
+		},
+		"reconnected player": {
+			a: []*Report{game("game-1", "h1", "q3dm17", "0:00", 3, player("Zeh", 2), player("Zeh", 1))},
+			b: []*Report{game("game-1", "h2", "q3dm17", "0:00", 3, player("Zeh", 3))},
+			same: 1,
+		},
+		"reconnected player with other stints": {
+			a: []*Report{game("game-1", "h1", "q3dm17", "0:00", 3, player("Zeh", 2), player("Zeh", 1))},
+			b: []*Report{game("game-1", "h2", "q3dm17", "0:00", 3, player("Zeh", 1), player("Zeh", 1))},
+			expected: []*GameDiff{{
+				Map: "q3dm17", StartTime: "0:00", GameA: "game-1", GameB: "game-1", MatchedBy: "time",
+				Players: []*PlayerDiff{
+					{Name: "Zeh", Changes: []Change{{Field: "Score", A: 3, B: 2}, {Field: "KillCount", A: 3, B: 2}}},
+				},
+			}},
+		},
+		"games only on one side": {
+			a:    []*Report{game("game-1", "h1", "q3dm17", "0:00", 3), game("game-2", "h2", "q3dm6", "5:00", 1)},
+			b:    []*Report{game("game-1", "h1", "q3dm17", "0:00", 3), game("game-2", "h3", "q3dm7", "5:00", 1)},
+			same: 1,
+			expected: []*GameDiff{
+				{Map: "q3dm6", StartTime: "5:00", GameA: "game-2", OnlyIn: "a"},
+				{Map: "q3dm7", StartTime: "5:00", GameB: "game-2", OnlyIn: "b"},
+			},
+		},
+		"reports without identity": {
+			a: []*Report{game("game-1", "", "", "", 2)},
+			b: []*Report{game("game-1", "h1", "q3dm17", "0:00", 3)},
+			expected: []*GameDiff{{
+				GameA: "game-1", GameB: "game-1", MatchedBy: "name",
+				Changes: []Change{{Field: "TotalKills", A: 2, B: 3}},
+			}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			diff := DiffReports(tc.a, tc.b)
+			if diff.Same != tc.same {
+				t.Errorf("Expected %d same games, but got %d", tc.same, diff.Same)
+			}
+			if tc.expected == nil {
+				tc.expected = []*GameDiff{}
+			}
+			if !reflect.DeepEqual(diff.Games, tc.expected) {
+				t.Errorf("Expected %+v, but got %+v", tc.expected, diff.Games)
+			}
+		})
+	}
+}
+
+func TestReadReports(t *testing.T) {
+	input := `{
+  "GameIdentifier": "game-1",
+  "GameHash": "h1",
+  "TotalKills": 3
+}
+{
+  "GameIdentifier": "game-2",
+  "TotalKills": 1
+}
+{
+  "TotalGames": 2,
+  "Players": {}
+}
+`
+	reps, err := ReadReports(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(reps) != 2 || reps[0].GameHash != "h1" || reps[1].GameIdentifier != "game-2" || reps[1].TotalKills != 1 {
+		t.Errorf("Expected 2 reports, but got %+v", reps)
+	}
+}
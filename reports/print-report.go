@@ -3,6 +3,8 @@ package reports
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/pedroegsilva/cw-test/parser"
 	"github.com/rs/zerolog/log"
@@ -15,28 +17,102 @@ type PlayerKills struct {
 
 type PlayerStatistics struct {
 	Name           string
+	Placement      int
 	Score          int
 	KillCount      int
+	TeamKillCount  int
 	FavoriteWeapon string
 	Nemesis        string
 	TargetPractice string
 	Vulnerability  string
+	// WorstTeammate is the teammate that killed the player the most.
+	WorstTeammate string
+	Team          parser.Team
+	Handicap      int
+	// TeamChanges and HandicapChanges are only set when the player changed
+	// them during the game.
+	TeamChanges     []parser.TeamChange     `json:",omitempty"`
+	HandicapChanges []parser.HandicapChange `json:",omitempty"`
+}
+
+// CTFStatistics is a line of the scoreboard of a CTF game.
+type CTFStatistics struct {
+	Name             string
+	Team             parser.Team
+	Score            int
+	Captures         int
+	Assists          int
+	Returns          int
+	FlagPickups      int
+	FlagCarrierKills int
+	CarryTime        string
+}
+
+// AccuracyStatistics is a line of the accuracy table, only set by the
+// dialects that log shots or hits.
+type AccuracyStatistics struct {
+	Name   string
+	Weapon string
+	Shots  int
+	Hits   int
+	// Accuracy is the share of the shots that hit.
+	Accuracy     float64
+	Kills        int
+	HitsReceived int
+	Headshots    int
+}
+
+// DamageStatistics is a line of the damage table.
+type DamageStatistics struct {
+	Name     string
+	Given    int
+	Received int
 }
 
 type Report struct {
-	GameIdentifier    string
+	GameIdentifier string
+	// GameHash, Map and StartTime identify the game across runs, see DiffReports.
+	GameHash  string
+	Map       string
+	StartTime string
+	// Source is the position of the file of the game among the files read,
+	// set by the diff command. It is not printed.
+	Source            string `json:"-"`
 	TotalKills        int
+	TotalTeamKills    int
 	EndingReason      string
+	ScoringRules      string
+	EndingCondition   parser.EndingCondition
+	Winner            string            `json:",omitempty"`
+	WinningTeam       string            `json:",omitempty"`
+	TeamScore         *parser.TeamScore `json:",omitempty"`
+	ResultNotes       []string          `json:",omitempty"`
+	Tags              parser.GameTag    `json:",omitempty"`
 	PlayersStatistics []*PlayerStatistics
-	WorldEnemy        string
-	KillCountByMeans  map[string]int
+	// CTFScoreboard is only set on CTF games, it includes the players that
+	// left the game.
+	CTFScoreboard []*CTFStatistics `json:",omitempty"`
+	// AccuracyTable and DamageTable are only set when the log has the
+	// Weapon_Stats or Hit lines of a mod.
+	AccuracyTable    []*AccuracyStatistics `json:",omitempty"`
+	DamageTable      []*DamageStatistics   `json:",omitempty"`
+	WorldEnemy       string
+	KillCountByMeans map[string]int
+	// KillCountByWeapon groups the kills by weapons by their family, e.g. rocketlauncher.
+	KillCountByWeapon map[string]int
+	// EnvironmentDeaths are the deaths caused by the map, such as lava and falling.
+	EnvironmentDeaths int
+	Anomalies         []string       `json:",omitempty"`
+	Awards            []parser.Award `json:",omitempty"`
 }
 
+// getTop returns the key with the highest value, ties are broken by the
+// lowest key so the reports are deterministic.
 func getTop(stat map[string]int) string {
 	top := "-"
 	topV := 0
 	for k, v := range stat {
-		if v > topV {
+		if v > topV || (v == topV && v > 0 && k < top) {
 			topV = v
 			top = k
 		}
@@ -44,66 +120,338 @@ func getTop(stat map[string]int) string {
 	return top
 }
 
-func getPlayerStatistics(players map[int]*parser.PlayersInfo) []*PlayerStatistics {
-	statistics := make([]*PlayerStatistics, len(players))
-	idx := 0
+// byFamily groups counts by means of death on their family, only for means
+// of the given classes.
+func byFamily(counts map[string]int, classes ...parser.MeansClass) map[string]int {
+	grouped := make(map[string]int)
+	for name, count := range counts {
+		if count == 0 {
+			continue
+		}
+		m, _ := parser.LookupMeans(name)
+		for _, class := range classes {
+			if m.Class == class {
+				grouped[m.Family] += count
+			}
+		}
+	}
+	return grouped
+}
+
+// PlayerFilter selects the players shown on the reports.
+type PlayerFilter func(info *parser.PlayersInfo) bool
+
+func allPlayers(info *parser.PlayersInfo) bool {
+	return true
+}
+
+// getPlayerStatistics has a line per player ordered by client id, followed by
+// the players that left the game in the order they left.
+func getPlayerStatistics(game *parser.Game, placements []parser.Placement, keep PlayerFilter) []*PlayerStatistics {
+	placeByName := make(map[string]int, len(placements))
+	for _, p := range placements {
+		placeByName[p.Name] = p.Place
+	}
+
+	ids := make([]int, 0, len(game.PlayersInfoById))
+	for id := range game.PlayersInfoById {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	players := make([]*parser.PlayersInfo, 0, len(ids)+len(game.DisconnectedPlayers))
+	for _, id := range ids {
+		players = append(players, game.PlayersInfoById[id])
+	}
+	players = append(players, game.DisconnectedPlayers...)
+
+	statistics := make([]*PlayerStatistics, 0, len(players))
 	for _, info := range players {
+		if !keep(info) {
+			continue
+		}
 		ps := PlayerStatistics{
 			Name:           info.Username,
+			Placement:      placeByName[info.Username],
 			Score:          info.Score,
 			KillCount:      info.KillCount,
-			FavoriteWeapon: getTop(info.KillCountByMean),
-			Nemesis:        getTop(info.DeathCountBySource),
+			TeamKillCount:  info.TeamKillCount,
+			FavoriteWeapon: getTop(byFamily(info.KillCountByMean, parser.MCWeapon)),
+			Nemesis:        getTop(withoutWorld(info.DeathCountBySource)),
 			TargetPractice: getTop(info.KillCountByPlayerTag),
-			Vulnerability:  getTop(info.DeathCountByWeapon),
+			Vulnerability:  getTop(byFamily(info.DeathCountByWeapon, parser.MCWeapon, parser.MCEnvironment, parser.MCSelf, parser.MCOther)),
+			WorstTeammate:  getTop(info.TeamDeathCountBySource),
+			Team:           info.Team,
+			Handicap:       info.Handicap,
+		}
+		if len(info.TeamChanges) > 1 {
+			ps.TeamChanges = info.TeamChanges
+		}
+		if len(info.HandicapChanges) > 1 {
+			ps.HandicapChanges = info.HandicapChanges
 		}
-		statistics[idx] = &ps
-		idx++
+		statistics = append(statistics, &ps)
 	}
 	return statistics
 }
 
-func createReportStructure(game *parser.Game, name string) *Report {
+// withoutWorld leaves the environment deaths out of the killers of a player.
+func withoutWorld(deathCountBySource map[string]int) map[string]int {
+	filtered := make(map[string]int, len(deathCountBySource))
+	for source, count := range deathCountBySource {
+		if source != "<world>" {
+			filtered[source] = count
+		}
+	}
+	return filtered
+}
+
+// getCTFScoreboard orders the players of a CTF game by team, captures and
+// score.
+func getCTFScoreboard(game *parser.Game, keep PlayerFilter) []*CTFStatistics {
+	var scoreboard []*CTFStatistics
+	add := func(info *parser.PlayersInfo) {
+		if info.Id == 0 || info.Id == parser.UnknownPlayerId || info.Username == "" || !keep(info) {
+			return
+		}
+		scoreboard = append(scoreboard, &CTFStatistics{
+			Name:             info.Username,
+			Team:             info.Team,
+			Score:            info.Score,
+			Captures:         info.CTF.Captures,
+			Assists:          info.CTF.Assists,
+			Returns:          info.CTF.Returns,
+			FlagPickups:      info.CTF.FlagPickups,
+			FlagCarrierKills: info.CTF.FlagCarrierKills,
+			CarryTime:        info.CTF.CarryTime.String(),
+		})
+	}
+	for _, info := range game.PlayersInfoById {
+		add(info)
+	}
+	for _, info := range game.DisconnectedPlayers {
+		add(info)
+	}
+	sort.Slice(scoreboard, func(i, j int) bool {
+		a, b := scoreboard[i], scoreboard[j]
+		if a.Team != b.Team {
+			return a.Team < b.Team
+		}
+		if a.Captures != b.Captures {
+			return a.Captures > b.Captures
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Name < b.Name
+	})
+	return scoreboard
+}
+
+// getAccuracyTables orders the accuracy and damage of the players by name and
+// weapon.
+func getAccuracyTables(game *parser.Game, keep PlayerFilter) ([]*AccuracyStatistics, []*DamageStatistics) {
+	var accuracy []*AccuracyStatistics
+	var damage []*DamageStatistics
+	add := func(info *parser.PlayersInfo) {
+		if info.Id == 0 || info.Id == parser.UnknownPlayerId || info.Username == "" || !keep(info) {
+			return
+		}
+		for weapon, a := range info.Weapons {
+			accuracy = append(accuracy, &AccuracyStatistics{
+				Name:         info.Username,
+				Weapon:       weapon,
+				Shots:        a.Shots,
+				Hits:         a.Hits,
+				Accuracy:     a.Accuracy(),
+				Kills:        a.Kills,
+				HitsReceived: a.HitsReceived,
+				Headshots:    a.Headshots,
+			})
+		}
+		if info.DamageGiven > 0 || info.DamageReceived > 0 {
+			damage = append(damage, &DamageStatistics{
+				Name:     info.Username,
+				Given:    info.DamageGiven,
+				Received: info.DamageReceived,
+			})
+		}
+	}
+	for _, info := range game.PlayersInfoById {
+		add(info)
+	}
+	for _, info := range game.DisconnectedPlayers {
+		add(info)
+	}
+	sort.Slice(accuracy, func(i, j int) bool {
+		if accuracy[i].Name != accuracy[j].Name {
+			return accuracy[i].Name < accuracy[j].Name
+		}
+		return accuracy[i].Weapon < accuracy[j].Weapon
+	})
+	sort.Slice(damage, func(i, j int) bool {
+		if damage[i].Given != damage[j].Given {
+			return damage[i].Given > damage[j].Given
+		}
+		return damage[i].Name < damage[j].Name
+	})
+	return accuracy, damage
+}
+
+// createReportStructure builds the report of the game, the player tables only
+// have the players selected by keep, or every player when it is nil.
+func createReportStructure(game *parser.Game, name string, keep PlayerFilter) *Report {
+	if keep == nil {
+		keep = allPlayers
+	}
 	filteredKillCountByMeans := make(map[string]int)
 	for means, count := range game.KillCountByMeans {
 		if count > 0 {
 			filteredKillCountByMeans[means] = count
 		}
 	}
+	environmentDeaths := 0
+	for _, count := range byFamily(game.KillCountByMeans, parser.MCEnvironment) {
+		environmentDeaths += count
+	}
+	var anomalies []string
+	for _, a := range game.Anomalies {
+		anomalies = append(anomalies, fmt.Sprintf("%s %s", a.Time, a.Message))
+	}
+	result := game.Result()
+	var ctfScoreboard []*CTFStatistics
+	if game.IsCTF() {
+		ctfScoreboard = getCTFScoreboard(game, keep)
+	}
+	accuracyTable, damageTable := getAccuracyTables(game, keep)
 	return &Report{
 		GameIdentifier:    name,
+		GameHash:          game.Hash(),
+		Map:               game.ServerConfig["mapname"],
+		StartTime:         game.StartTime,
 		TotalKills:        game.TotalKills,
+		TotalTeamKills:    game.TotalTeamKills,
 		EndingReason:      game.EndingReason,
+		ScoringRules:      game.ScoringRules,
+		EndingCondition:   result.Condition,
+		Winner:            result.Winner,
+		WinningTeam:       result.WinningTeam,
+		TeamScore:         result.TeamScore,
+		ResultNotes:       result.Notes,
+		Tags:              game.Tags,
 		WorldEnemy:        getTop(game.WorldKillStatus.KillCountByPlayerTag),
-		PlayersStatistics: getPlayerStatistics(game.PlayersInfoById),
+		PlayersStatistics: getPlayerStatistics(game, result.Placements, keep),
+		CTFScoreboard:     ctfScoreboard,
+		AccuracyTable:     accuracyTable,
+		DamageTable:       damageTable,
 		KillCountByMeans:  filteredKillCountByMeans,
+		KillCountByWeapon: byFamily(game.KillCountByMeans, parser.MCWeapon),
+		EnvironmentDeaths: environmentDeaths,
+		Anomalies:         anomalies,
+		Awards:            game.Awards,
 	}
 }
 
-func PrintHumanReadableReport(game *parser.Game, name string) {
-	report := createReportStructure(game, name)
+// NewReport builds the report of the game with every player.
+func NewReport(game *parser.Game, name string) *Report {
+	return createReportStructure(game, name, nil)
+}
+
+func PrintHumanReadableReport(game *parser.Game, name string, keep PlayerFilter) {
+	report := createReportStructure(game, name, keep)
 	fmt.Printf("-------------------- %s --------------------\n", report.GameIdentifier)
 	fmt.Println("Total kills:", report.TotalKills)
+	if report.TotalTeamKills > 0 {
+		fmt.Println("Total team kills:", report.TotalTeamKills)
+	}
 	fmt.Println("Game Ending Event:", report.EndingReason)
+	fmt.Println("Scoring Rules:", report.ScoringRules)
+	fmt.Println("Ending Condition:", report.EndingCondition)
+	if report.TeamScore != nil {
+		fmt.Printf("Team Score: red %d, blue %d\n", report.TeamScore.Red, report.TeamScore.Blue)
+	}
+	if report.WinningTeam != "" {
+		fmt.Println("Winning Team:", report.WinningTeam)
+	}
+	if report.Winner != "" {
+		fmt.Println("Winner:", report.Winner)
+	}
+	for _, note := range report.ResultNotes {
+		fmt.Println("Result Note:", note)
+	}
+	if report.Tags != 0 {
+		fmt.Println("Tags:", strings.Join(report.Tags.Names(), ", "))
+	}
 	fmt.Println("World Enemy:", report.WorldEnemy)
 	fmt.Println("Kill Means:")
 	for m, c := range report.KillCountByMeans {
 		fmt.Printf("  %s: %d\n", m, c)
 	}
+	fmt.Println("Kills by Weapon:")
+	for w, c := range report.KillCountByWeapon {
+		fmt.Printf("  %s: %d\n", w, c)
+	}
+	fmt.Println("Environment Deaths:", report.EnvironmentDeaths)
 	fmt.Println("Player Statistics:")
 	for _, ps := range report.PlayersStatistics {
 		fmt.Println(" ", ps.Name)
+		fmt.Println("    Placement:", ps.Placement)
 		fmt.Println("    Score:", ps.Score)
 		fmt.Println("    Kill Count:", ps.KillCount)
+		fmt.Println("    Team Kill Count:", ps.TeamKillCount)
 		fmt.Println("    Nemesis:", ps.Nemesis)
 		fmt.Println("    Target Practice:", ps.TargetPractice)
 		fmt.Println("    Favorite Weapon:", ps.FavoriteWeapon)
 		fmt.Println("    Vulnerability:", ps.Vulnerability)
+		fmt.Println("    Worst Teammate:", ps.WorstTeammate)
+		fmt.Println("    Team:", ps.Team)
+		fmt.Println("    Handicap:", ps.Handicap)
+		if len(ps.TeamChanges) > 0 {
+			changes := make([]string, len(ps.TeamChanges))
+			for i, c := range ps.TeamChanges {
+				changes[i] = fmt.Sprintf("%s %s", c.Time, c.Team)
+			}
+			fmt.Println("    Team Changes:", strings.Join(changes, ", "))
+		}
+		if len(ps.HandicapChanges) > 0 {
+			changes := make([]string, len(ps.HandicapChanges))
+			for i, c := range ps.HandicapChanges {
+				changes[i] = fmt.Sprintf("%s %d", c.Time, c.Handicap)
+			}
+			fmt.Println("    Handicap Changes:", strings.Join(changes, ", "))
+		}
+	}
+	if len(report.CTFScoreboard) > 0 {
+		fmt.Println("CTF Scoreboard:")
+		fmt.Printf("  %-20s %-9s %5s %4s %4s %4s %4s %4s %9s\n", "Name", "Team", "Score", "Caps", "Ast", "Ret", "Pick", "FCK", "Carry")
+		for _, cs := range report.CTFScoreboard {
+			fmt.Printf("  %-20s %-9s %5d %4d %4d %4d %4d %4d %9s\n", cs.Name, cs.Team, cs.Score, cs.Captures, cs.Assists, cs.Returns, cs.FlagPickups, cs.FlagCarrierKills, cs.CarryTime)
+		}
+	}
+	if len(report.AccuracyTable) > 0 {
+		fmt.Println("Accuracy:")
+		fmt.Printf("  %-20s %-16s %6s %6s %6s %5s %6s %5s\n", "Name", "Weapon", "Shots", "Hits", "Acc%", "Kills", "Recvd", "Head")
+		for _, as := range report.AccuracyTable {
+			fmt.Printf("  %-20s %-16s %6d %6d %6.1f %5d %6d %5d\n", as.Name, as.Weapon, as.Shots, as.Hits, as.Accuracy*100, as.Kills, as.HitsReceived, as.Headshots)
+		}
+	}
+	if len(report.DamageTable) > 0 {
+		fmt.Println("Damage:")
+		fmt.Printf("  %-20s %8s %8s\n", "Name", "Given", "Received")
+		for _, ds := range report.DamageTable {
+			fmt.Printf("  %-20s %8d %8d\n", ds.Name, ds.Given, ds.Received)
+		}
+	}
+	printAwards("Awards:", report.Awards)
+	if len(report.Anomalies) > 0 {
+		fmt.Println("Anomalies:")
+		for _, a := range report.Anomalies {
+			fmt.Println(" ", a)
+		}
 	}
 }
 
-func PrintJson(game *parser.Game, name string) {
-	report := createReportStructure(game, name)
+func PrintJson(game *parser.Game, name string, keep PlayerFilter) {
+	report := createReportStructure(game, name, keep)
 	jsonData, err := json.MarshalIndent(report, "", "  ")
 	if err != nil {
 		log.Error().Msg(fmt.Sprintf("could not format json report. game: %s | err: %s", name, err))
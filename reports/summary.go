@@ -0,0 +1,243 @@
+package reports
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/pedroegsilva/cw-test/parser"
+	"github.com/rs/zerolog/log"
+)
+
+type PlayerSummary struct {
+	Name      string
+	Games     int
+	Wins      int
+	Score     int
+	KillCount int
+	// TeamKillCount are the kills of teammates, they are not on KillCount.
+	TeamKillCount int
+	DeathCount    int
+	SuicideCount  int
+}
+
+// Summary aggregates the statistics of many games. Players are matched by
+// username since the client ids are only valid inside a game.
+type Summary struct {
+	Games            int
+	TotalKills       int
+	KillCountByMeans map[string]int
+	// KillCountByWeapon groups the kills by weapons by their family.
+	KillCountByWeapon map[string]int
+	// AwardRules are the rules of the season awards, parser.DefaultAwardRules
+	// by default.
+	AwardRules    []parser.AwardRule
+	playersByName map[string]*PlayerSummary
+	awardStats    map[string]parser.AwardStats
+}
+
+func NewSummary() *Summary {
+	return &Summary{
+		KillCountByMeans:  make(map[string]int),
+		KillCountByWeapon: make(map[string]int),
+		AwardRules:        parser.DefaultAwardRules(),
+		playersByName:     make(map[string]*PlayerSummary),
+		awardStats:        make(map[string]parser.AwardStats),
+	}
+}
+
+// AddGame adds the statistics of a game. Only the players kept by keep are
+// added, nil keeps every player.
+func (s *Summary) AddGame(game *parser.Game, keep PlayerFilter) {
+	shown := keptNames(game, keep)
+	s.Games++
+	s.TotalKills += game.TotalKills
+	for means, count := range game.KillCountByMeans {
+		if count > 0 {
+			s.KillCountByMeans[means] += count
+		}
+	}
+	for family, count := range byFamily(game.KillCountByMeans, parser.MCWeapon) {
+		s.KillCountByWeapon[family] += count
+	}
+	if winner := game.Result().Winner; winner != "" && shown(winner) {
+		s.player(winner).Wins++
+	}
+	// a player that connected again has an entry for each connection, the
+	// game is counted once
+	played := make(map[string]bool)
+	add := func(info *parser.PlayersInfo) {
+		if info.Id == 0 || info.Id == parser.UnknownPlayerId || info.Username == "" || !shown(info.Username) {
+			return
+		}
+		ps := s.player(info.Username)
+		if !played[info.Username] {
+			played[info.Username] = true
+			ps.Games++
+		}
+		ps.Score += info.Score
+		ps.KillCount += info.KillCount
+		ps.TeamKillCount += info.TeamKillCount
+		ps.DeathCount += info.DeathCount
+		ps.SuicideCount += info.SuicideCount
+	}
+	for _, info := range game.PlayersInfoById {
+		add(info)
+	}
+	for _, info := range game.DisconnectedPlayers {
+		add(info)
+	}
+	stats := game.AwardStats()
+	for name := range stats {
+		if !shown(name) {
+			delete(stats, name)
+		}
+	}
+	s.addAwardStats(stats)
+}
+
+// keptNames tells if a player of the game, by name, is kept by keep. A player
+// that connected again is kept when any of its connections is.
+func keptNames(game *parser.Game, keep PlayerFilter) func(name string) bool {
+	if keep == nil {
+		return func(string) bool { return true }
+	}
+	kept := make(map[string]bool)
+	for _, info := range game.PlayersInfoById {
+		if keep(info) {
+			kept[info.Username] = true
+		}
+	}
+	for _, info := range game.DisconnectedPlayers {
+		if keep(info) {
+			kept[info.Username] = true
+		}
+	}
+	return func(name string) bool { return kept[name] }
+}
+
+func (s *Summary) addAwardStats(statsByName map[string]parser.AwardStats) {
+	for name, stats := range statsByName {
+		season, ok := s.awardStats[name]
+		if !ok {
+			season = make(parser.AwardStats)
+			s.awardStats[name] = season
+		}
+		season.Add(stats)
+	}
+}
+
+// Merge adds the statistics of other into s.
+func (s *Summary) Merge(other *Summary) {
+	s.Games += other.Games
+	s.TotalKills += other.TotalKills
+	for means, count := range other.KillCountByMeans {
+		s.KillCountByMeans[means] += count
+	}
+	for family, count := range other.KillCountByWeapon {
+		s.KillCountByWeapon[family] += count
+	}
+	for name, op := range other.playersByName {
+		ps := s.player(name)
+		ps.Games += op.Games
+		ps.Wins += op.Wins
+		ps.Score += op.Score
+		ps.KillCount += op.KillCount
+		ps.TeamKillCount += op.TeamKillCount
+		ps.DeathCount += op.DeathCount
+		ps.SuicideCount += op.SuicideCount
+	}
+	s.addAwardStats(other.awardStats)
+}
+
+// Awards returns the season awards of the players on every game.
+func (s *Summary) Awards() []parser.Award {
+	return parser.GiveAwards(s.AwardRules, parser.AwardScopeSeason, s.awardStats)
+}
+
+// Players returns the players ordered by score and name.
+func (s *Summary) Players() []*PlayerSummary {
+	players := make([]*PlayerSummary, 0, len(s.playersByName))
+	for _, ps := range s.playersByName {
+		players = append(players, ps)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		if players[i].Score != players[j].Score {
+			return players[i].Score > players[j].Score
+		}
+		return players[i].Name < players[j].Name
+	})
+	return players
+}
+
+func (s *Summary) player(name string) *PlayerSummary {
+	ps, ok := s.playersByName[name]
+	if !ok {
+		ps = &PlayerSummary{Name: name}
+		s.playersByName[name] = ps
+	}
+	return ps
+}
+
+func (s *Summary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Games             int
+		TotalKills        int
+		KillCountByMeans  map[string]int
+		KillCountByWeapon map[string]int
+		Players           []*PlayerSummary
+		Awards            []parser.Award `json:",omitempty"`
+	}{
+		Games:             s.Games,
+		TotalKills:        s.TotalKills,
+		KillCountByMeans:  s.KillCountByMeans,
+		KillCountByWeapon: s.KillCountByWeapon,
+		Players:           s.Players(),
+		Awards:            s.Awards(),
+	})
+}
+
+func PrintHumanReadableSummary(summary *Summary) {
+	fmt.Println("-------------------- summary --------------------")
+	fmt.Println("Games:", summary.Games)
+	fmt.Println("Total kills:", summary.TotalKills)
+	fmt.Println("Kill Means:")
+	for m, c := range summary.KillCountByMeans {
+		fmt.Printf("  %s: %d\n", m, c)
+	}
+	fmt.Println("Kills by Weapon:")
+	for w, c := range summary.KillCountByWeapon {
+		fmt.Printf("  %s: %d\n", w, c)
+	}
+	fmt.Println("Players:")
+	for _, ps := range summary.Players() {
+		fmt.Println(" ", ps.Name)
+		fmt.Println("    Games:", ps.Games)
+		fmt.Println("    Wins:", ps.Wins)
+		fmt.Println("    Score:", ps.Score)
+		fmt.Println("    Kill Count:", ps.KillCount)
+		fmt.Println("    Team Kill Count:", ps.TeamKillCount)
+		fmt.Println("    Death Count:", ps.DeathCount)
+		fmt.Println("    Suicide Count:", ps.SuicideCount)
+	}
+	printAwards("Season Awards:", summary.Awards())
+}
+
+func printAwards(title string, awards []parser.Award) {
+	if len(awards) == 0 {
+		return
+	}
+	fmt.Println(title)
+	for _, a := range awards {
+		fmt.Printf("  %s: %s (%s, %g)\n", a.Name, a.Player, a.Description, a.Value)
+	}
+}
+
+func PrintSummaryJson(summary *Summary) {
+	jsonData, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("could not format json summary. err: %s", err))
+		return
+	}
+	fmt.Println(string(jsonData))
+}
@@ -0,0 +1,84 @@
+package reports
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+func TestSummaryDisconnectedPlayers(t *testing.T) {
+	input := `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:05 Kill: 3 2 10: Zeh killed Isgalamido by MOD_RAILGUN
+  0:06 ClientDisconnect: 3
+  0:07 ClientConnect: 3
+  0:07 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:08 Kill: 3 2 10: Zeh killed Isgalamido by MOD_RAILGUN
+  0:09 ClientDisconnect: 3
+  1:10 ShutdownGame:
+`
+	gs := parser.InitScanner(bufio.NewScanner(strings.NewReader(input)))
+	game, _, err := gs.GetGame()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	summary := NewSummary()
+	summary.AddGame(game, nil)
+
+	players := summary.Players()
+	if len(players) != 2 {
+		t.Fatalf("Expected 2 players, but got %d", len(players))
+	}
+	zeh := summary.player("Zeh")
+	if zeh.Games != 1 || zeh.KillCount != 2 || zeh.KillCount+summary.player("Isgalamido").KillCount != summary.TotalKills {
+		t.Errorf("Expected Zeh with 1 game and the 2 kills, but got %+v", zeh)
+	}
+
+	report := NewReport(game, "game-1")
+	kills := 0
+	for _, ps := range report.PlayersStatistics {
+		if ps.Name == "Zeh" {
+			kills += ps.KillCount
+		}
+	}
+	if kills != 2 {
+		t.Errorf("Expected the report to have the 2 kills of Zeh, but got %d", kills)
+	}
+}
+
+func TestSummaryKeep(t *testing.T) {
+	input := `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:05 Kill: 3 2 10: Zeh killed Isgalamido by MOD_RAILGUN
+  0:06 Kill: 3 2 10: Zeh killed Isgalamido by MOD_RAILGUN
+  1:10 ShutdownGame:
+`
+	gs := parser.InitScanner(bufio.NewScanner(strings.NewReader(input)))
+	game, _, err := gs.GetGame()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	summary := NewSummary()
+	summary.AddGame(game, func(info *parser.PlayersInfo) bool { return info.KillCount == 0 })
+
+	players := summary.Players()
+	if len(players) != 1 || players[0].Name != "Isgalamido" || players[0].Wins != 0 {
+		t.Errorf("Expected only Isgalamido without wins, but got %+v", players)
+	}
+	for _, award := range summary.Awards() {
+		if award.Player == "Zeh" {
+			t.Errorf("Expected no awards for Zeh, but got %+v", award)
+		}
+	}
+	if summary.TotalKills != 2 {
+		t.Errorf("Expected the 2 kills of the game, but got %d", summary.TotalKills)
+	}
+}
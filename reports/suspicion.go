@@ -0,0 +1,289 @@
+package reports
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pedroegsilva/cw-test/parser"
+	"github.com/rs/zerolog/log"
+)
+
+// SuspicionOptions are the thresholds of the suspicion report. A player is
+// only flagged when a threshold is crossed, so raising them makes the report
+// quieter.
+type SuspicionOptions struct {
+	// MaxKillsPerMinute is the highest kill rate expected from a human. The
+	// kill rate and the history are only checked after MinKills kills.
+	MaxKillsPerMinute float64
+	MinKills          int
+	// MaxRailgunShare is the highest share of the weapon kills done with the
+	// railgun, it is only checked after MinRailgunKills railgun kills.
+	MaxRailgunShare float64
+	MinRailgunKills int
+	// MinKillInterval is the shortest time expected between two kills with
+	// different weapons, a player is flagged after MaxQuickKills quicker ones.
+	// The log times have a resolution of a second, so the default of 1s only
+	// flags kills with different weapons on the same second.
+	MinKillInterval time.Duration
+	MaxQuickKills   int
+	// HighPing is the ping from which leading the kills is suspicious, and
+	// MaxPingSwing is the largest change of ping expected within a game.
+	HighPing     int
+	MaxPingSwing int
+	// MaxHistoryDeviations is how many standard deviations above the mean
+	// of the past kill/death ratios of the player a game can be, once the
+	// player has MinHistoryGames games.
+	MaxHistoryDeviations float64
+	MinHistoryGames      int
+}
+
+func DefaultSuspicionOptions() SuspicionOptions {
+	return SuspicionOptions{
+		MaxKillsPerMinute:    6,
+		MinKills:             10,
+		MaxRailgunShare:      0.8,
+		MinRailgunKills:      10,
+		MinKillInterval:      time.Second,
+		MaxQuickKills:        3,
+		HighPing:             300,
+		MaxPingSwing:         250,
+		MaxHistoryDeviations: 3,
+		MinHistoryGames:      5,
+	}
+}
+
+// minHistoryDeviation keeps players with very steady ratios from being
+// flagged on a small change.
+const minHistoryDeviation = 0.25
+
+// Suspicion is a flag raised on a player in a game. It is a hint for an
+// admin to review the game, not a proof of cheating.
+type Suspicion struct {
+	Game   string
+	Player string
+	// Flag is the check that raised the suspicion: kill-rate,
+	// railgun-share, quick-kills, high-ping, ping-swing or history.
+	Flag        string
+	Value       float64
+	Threshold   float64
+	Explanation string
+}
+
+// SuspicionReport checks each game for statistical outliers and keeps the
+// kill/death ratio of the players to compare them with their history.
+type SuspicionReport struct {
+	opts       SuspicionOptions
+	history    map[string][]float64
+	suspicions []*Suspicion
+}
+
+func NewSuspicionReport(opts SuspicionOptions) *SuspicionReport {
+	return &SuspicionReport{opts: opts, history: make(map[string][]float64)}
+}
+
+// SeedHistory adds the kill/death ratios of past games of a player, e.g.
+// from the stats store, before the games of the report.
+func (r *SuspicionReport) SeedHistory(player string, ratios []float64) {
+	r.history[player] = append(r.history[player], ratios...)
+}
+
+func (r *SuspicionReport) flag(game, player, flag string, value, threshold float64, explanation string) {
+	r.suspicions = append(r.suspicions, &Suspicion{
+		Game:        game,
+		Player:      player,
+		Flag:        flag,
+		Value:       value,
+		Threshold:   threshold,
+		Explanation: explanation,
+	})
+}
+
+// AddGame checks the human players of a game, the games must be added in the
+// order they were played for the history check. Only the players kept by keep
+// are flagged, nil keeps every player, but every player adds to the history.
+func (r *SuspicionReport) AddGame(game *parser.Game, name string, keep PlayerFilter) {
+	var players []*parser.PlayersInfo
+	for _, info := range game.PlayersInfoById {
+		players = append(players, info)
+	}
+	players = append(players, game.DisconnectedPlayers...)
+	sort.Slice(players, func(i, j int) bool {
+		if players[i].Username != players[j].Username {
+			return players[i].Username < players[j].Username
+		}
+		return players[i].Id < players[j].Id
+	})
+
+	mostKills := 0
+	for _, info := range players {
+		if info.KillCount > mostKills {
+			mostKills = info.KillCount
+		}
+	}
+
+	quickKills := r.quickKills(game)
+	duration, known := game.Duration()
+	for _, info := range players {
+		if info.Id == 0 || info.Id == parser.UnknownPlayerId || info.Username == "" || info.Bot {
+			continue
+		}
+		player := info.Username
+		flag := func(flag string, value float64, threshold float64, explanation string) {
+			if keep == nil || keep(info) {
+				r.flag(name, player, flag, value, threshold, explanation)
+			}
+		}
+
+		if known && duration >= time.Minute && info.KillCount >= r.opts.MinKills {
+			rate := float64(info.KillCount) / duration.Minutes()
+			if rate > r.opts.MaxKillsPerMinute {
+				flag("kill-rate", rate, r.opts.MaxKillsPerMinute,
+					fmt.Sprintf("%d kills in %s is %.1f kills per minute, above the %.1f expected from a human", info.KillCount, duration, rate, r.opts.MaxKillsPerMinute))
+			}
+		}
+
+		railgun, weapon := 0, 0
+		for means, count := range info.KillCountByMean {
+			m, _ := parser.LookupMeans(means)
+			if m.Class != parser.MCWeapon {
+				continue
+			}
+			weapon += count
+			if m.Family == "railgun" {
+				railgun += count
+			}
+		}
+		if railgun >= r.opts.MinRailgunKills {
+			share := float64(railgun) / float64(weapon)
+			if share > r.opts.MaxRailgunShare {
+				flag("railgun-share", share, r.opts.MaxRailgunShare,
+					fmt.Sprintf("%d of %d weapon kills with the railgun (%.0f%%), above the %.0f%% expected; aimbots favour hitscan weapons", railgun, weapon, share*100, r.opts.MaxRailgunShare*100))
+			}
+		}
+
+		if count := quickKills[player]; count >= r.opts.MaxQuickKills {
+			flag("quick-kills", float64(count), float64(r.opts.MaxQuickKills),
+				fmt.Sprintf("%d kills less than %s after the previous one with another means, switching weapons takes longer", count, r.opts.MinKillInterval))
+		}
+
+		if len(info.Pings) > 0 {
+			low, high := info.Pings[0], info.Pings[0]
+			for _, ping := range info.Pings {
+				low, high = min(low, ping), max(high, ping)
+			}
+			if high >= r.opts.HighPing && info.KillCount == mostKills && mostKills > 0 {
+				flag("high-ping", float64(high), float64(r.opts.HighPing),
+					fmt.Sprintf("ping of %d while leading the kills with %d; lagging players rarely lead, it may be a lag switch", high, info.KillCount))
+			}
+			if high-low > r.opts.MaxPingSwing {
+				flag("ping-swing", float64(high-low), float64(r.opts.MaxPingSwing),
+					fmt.Sprintf("ping changed from %d to %d during the game, it may be a faked lag", low, high))
+			}
+		}
+
+		ratio := float64(info.KillCount)
+		if info.DeathCount > 0 {
+			ratio = float64(info.KillCount) / float64(info.DeathCount)
+		}
+		if past := r.history[player]; len(past) >= r.opts.MinHistoryGames && info.KillCount >= r.opts.MinKills {
+			mean, deviation := meanDeviation(past)
+			deviation = math.Max(deviation, minHistoryDeviation)
+			if deviations := (ratio - mean) / deviation; deviations > r.opts.MaxHistoryDeviations {
+				flag("history", deviations, r.opts.MaxHistoryDeviations,
+					fmt.Sprintf("kill/death ratio of %.2f is %.1f standard deviations above the %.2f average of the %d previous games of the player", ratio, deviations, mean, len(past)))
+			}
+		}
+		r.history[player] = append(r.history[player], ratio)
+	}
+}
+
+// quickKills counts the kills of each player done before MinKillInterval
+// since the previous kill of the player. Kills with the same weapon family are
+// left out, as a single rocket or rail can kill many players, directly or by
+// splash.
+func (r *SuspicionReport) quickKills(game *parser.Game) map[string]int {
+	counts := make(map[string]int)
+	last := make(map[string]parser.KillRecord)
+	for _, kill := range game.Kills {
+		if kill.KillerId == 0 || kill.Killer == kill.Victim || kill.TeamKill {
+			continue
+		}
+		if previous, ok := last[kill.Killer]; ok && !sameFamily(previous.Means, kill.Means) {
+			if elapsed, ok := elapsedBetween(previous.Time, kill.Time); ok && elapsed < r.opts.MinKillInterval {
+				counts[kill.Killer]++
+			}
+		}
+		last[kill.Killer] = kill
+	}
+	return counts
+}
+
+func sameFamily(a, b string) bool {
+	ma, _ := parser.LookupMeans(a)
+	mb, _ := parser.LookupMeans(b)
+	return ma.Family == mb.Family
+}
+
+func elapsedBetween(from string, to string) (time.Duration, bool) {
+	start, err := parser.ParseTime(from)
+	if err != nil {
+		return 0, false
+	}
+	end, err := parser.ParseTime(to)
+	if err != nil || end < start {
+		return 0, false
+	}
+	return end - start, true
+}
+
+func meanDeviation(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+// Suspicions returns the flags ordered by player, the flags of a player keep
+// the order the games were added.
+func (r *SuspicionReport) Suspicions() []*Suspicion {
+	suspicions := make([]*Suspicion, len(r.suspicions))
+	copy(suspicions, r.suspicions)
+	sort.SliceStable(suspicions, func(i, j int) bool {
+		return suspicions[i].Player < suspicions[j].Player
+	})
+	return suspicions
+}
+
+func PrintHumanReadableSuspicions(suspicions []*Suspicion) {
+	fmt.Println("-------------------- suspicions --------------------")
+	if len(suspicions) == 0 {
+		fmt.Println("  nothing to review")
+		return
+	}
+	player := ""
+	for _, s := range suspicions {
+		if s.Player != player {
+			player = s.Player
+			fmt.Println(" ", player)
+		}
+		fmt.Printf("    %s %s: %s\n", s.Game, s.Flag, s.Explanation)
+	}
+}
+
+func PrintSuspicionsJson(suspicions []*Suspicion) {
+	jsonData, err := json.MarshalIndent(suspicions, "", "  ")
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("could not format json suspicions. err: %s", err))
+		return
+	}
+	fmt.Println(string(jsonData))
+}
@@ -0,0 +1,97 @@
+package reports
+
+import (
+	"bufio"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+func TestSuspicionReport(t *testing.T) {
+	var lines []string
+	lines = append(lines,
+		`  0:00 InitGame: \g_gametype\0\mapname\q3dm17`,
+		`  0:01 ClientConnect: 2`,
+		`  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0`,
+		`  0:01 ClientConnect: 3`,
+		`  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0`,
+	)
+	// 14 railgun kills in a minute, the first 3 pairs followed by a rocket
+	// kill on the same second
+	for i := 0; i < 7; i++ {
+		lines = append(lines,
+			fmt.Sprintf("  0:%02d Kill: 2 3 10: Isgalamido killed Zeh by MOD_RAILGUN", 10+i*5),
+			fmt.Sprintf("  0:%02d Kill: 2 3 10: Isgalamido killed Zeh by MOD_RAILGUN", 11+i*5),
+		)
+		if i < 3 {
+			lines = append(lines, fmt.Sprintf("  0:%02d Kill: 2 3 6: Isgalamido killed Zeh by MOD_ROCKET", 11+i*5))
+		}
+	}
+	lines = append(lines,
+		`  1:01 Exit: Fraglimit hit.`,
+		`  1:01 score: 18  ping: 350  client: 2 Isgalamido`,
+		`  1:01 score: 0  ping: 20  client: 3 Zeh`,
+		`  1:01 ShutdownGame:`,
+	)
+	gs := parser.InitScanner(bufio.NewScanner(strings.NewReader(strings.Join(lines, "\n"))))
+	game, _, err := gs.GetGame()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	report := NewSuspicionReport(DefaultSuspicionOptions())
+	report.SeedHistory("Isgalamido", []float64{1, 1.5, 0.5, 1, 1})
+	report.AddGame(game, "game-1", nil)
+
+	var flags []string
+	for _, s := range report.Suspicions() {
+		if s.Player != "Isgalamido" || s.Game != "game-1" || s.Explanation == "" {
+			t.Errorf("Unexpected suspicion %+v", s)
+		}
+		flags = append(flags, s.Flag)
+	}
+	expected := []string{"kill-rate", "railgun-share", "quick-kills", "high-ping", "history"}
+	if !reflect.DeepEqual(flags, expected) {
+		t.Errorf("Expected %v, but got %v", expected, flags)
+	}
+
+	quiet := NewSuspicionReport(DefaultSuspicionOptions())
+	quiet.AddGame(game, "game-1", nil)
+	if suspicions := quiet.Suspicions(); len(suspicions) != len(expected)-1 {
+		t.Errorf("Expected no history flag without history, but got %d flags", len(suspicions))
+	}
+}
+
+func TestQuickKillsSplash(t *testing.T) {
+	var lines []string
+	lines = append(lines,
+		`  0:00 InitGame: \g_gametype\0\mapname\q3dm17`,
+		`  0:01 ClientConnect: 2`,
+		`  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0`,
+		`  0:01 ClientConnect: 3`,
+		`  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0`,
+		`  0:01 ClientConnect: 4`,
+		`  0:01 ClientUserinfoChanged: 4 n\Mocinha\t\0`,
+	)
+	// a rocket that kills both players, directly and by splash
+	for i := 0; i < 5; i++ {
+		lines = append(lines,
+			fmt.Sprintf("  0:%02d Kill: 2 3 6: Isgalamido killed Zeh by MOD_ROCKET", 10+i*5),
+			fmt.Sprintf("  0:%02d Kill: 2 4 7: Isgalamido killed Mocinha by MOD_ROCKET_SPLASH", 10+i*5),
+		)
+	}
+	lines = append(lines, `  1:01 ShutdownGame:`)
+	gs := parser.InitScanner(bufio.NewScanner(strings.NewReader(strings.Join(lines, "\n"))))
+	game, _, err := gs.GetGame()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	report := NewSuspicionReport(DefaultSuspicionOptions())
+	if counts := report.quickKills(game); counts["Isgalamido"] != 0 {
+		t.Errorf("Expected no quick kills with a single weapon, but got %d", counts["Isgalamido"])
+	}
+}
@@ -0,0 +1,282 @@
+package reports
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pedroegsilva/cw-test/parser"
+	"github.com/rs/zerolog/log"
+)
+
+// ammoFamily maps the ammo items to the family of the weapon that uses them.
+var ammoFamily = map[string]string{
+	"ammo_shells":    "shotgun",
+	"ammo_bullets":   "machinegun",
+	"ammo_grenades":  "grenadelauncher",
+	"ammo_cells":     "plasmagun",
+	"ammo_lightning": "lightning",
+	"ammo_rockets":   "rocketlauncher",
+	"ammo_slugs":     "railgun",
+	"ammo_bfg":       "bfg",
+	"ammo_nails":     "nailgun",
+	"ammo_mines":     "prox_launcher",
+	"ammo_belt":      "chaingun",
+}
+
+// WeaponStats is the efficiency of a player with a weapon family. Most logs
+// have no shots, so pickups are used as a proxy of the use of the weapon.
+type WeaponStats struct {
+	Player        string
+	Weapon        string
+	WeaponPickups int
+	AmmoPickups   int
+	Kills         int
+	DirectKills   int
+	SplashKills   int
+	// Shots and Hits are only set by the dialects that log them, Accuracy is
+	// the share of the shots that hit.
+	Shots    int     `json:",omitempty"`
+	Hits     int     `json:",omitempty"`
+	Accuracy float64 `json:",omitempty"`
+	// KillsPerPickup is the kills over the weapon pickups, or the kills when
+	// the weapon was never picked up, e.g. the machinegun of the spawn.
+	KillsPerPickup float64
+	// SplashShare is the share of the kills done by splash damage.
+	SplashShare float64
+	// PickupsWithKill are the pickups followed by a kill with the weapon
+	// before the player died.
+	PickupsWithKill int
+	// AvgSecondsToFirstKill is the average time from those pickups to the
+	// first kill with the weapon.
+	AvgSecondsToFirstKill float64
+	timeToFirstKill       time.Duration
+}
+
+func (ws *WeaponStats) add(other *WeaponStats) {
+	ws.WeaponPickups += other.WeaponPickups
+	ws.AmmoPickups += other.AmmoPickups
+	ws.Kills += other.Kills
+	ws.DirectKills += other.DirectKills
+	ws.SplashKills += other.SplashKills
+	ws.Shots += other.Shots
+	ws.Hits += other.Hits
+	ws.PickupsWithKill += other.PickupsWithKill
+	ws.timeToFirstKill += other.timeToFirstKill
+	ws.ratios()
+}
+
+func (ws *WeaponStats) ratios() {
+	ws.KillsPerPickup = float64(ws.Kills)
+	if ws.WeaponPickups > 0 {
+		ws.KillsPerPickup = float64(ws.Kills) / float64(ws.WeaponPickups)
+	}
+	ws.SplashShare = 0
+	if ws.Kills > 0 {
+		ws.SplashShare = float64(ws.SplashKills) / float64(ws.Kills)
+	}
+	ws.Accuracy = 0
+	if ws.Shots > 0 {
+		ws.Accuracy = float64(ws.Hits) / float64(ws.Shots)
+	}
+	ws.AvgSecondsToFirstKill = 0
+	if ws.PickupsWithKill > 0 {
+		ws.AvgSecondsToFirstKill = ws.timeToFirstKill.Seconds() / float64(ws.PickupsWithKill)
+	}
+}
+
+// WeaponSummary aggregates the weapon stats of many games by player and
+// weapon family.
+type WeaponSummary struct {
+	stats map[string]map[string]*WeaponStats
+}
+
+func NewWeaponSummary() *WeaponSummary {
+	return &WeaponSummary{stats: make(map[string]map[string]*WeaponStats)}
+}
+
+func (s *WeaponSummary) get(player string, weapon string) *WeaponStats {
+	byWeapon, ok := s.stats[player]
+	if !ok {
+		byWeapon = make(map[string]*WeaponStats)
+		s.stats[player] = byWeapon
+	}
+	ws, ok := byWeapon[weapon]
+	if !ok {
+		ws = &WeaponStats{Player: player, Weapon: weapon}
+		byWeapon[weapon] = ws
+	}
+	return ws
+}
+
+// timelineEntry is an item pickup or a kill, ordered by time.
+type timelineEntry struct {
+	time time.Duration
+	item *parser.ItemRecord
+	kill *parser.KillRecord
+}
+
+// AddGame adds the weapon stats of a game. A pickup of a weapon the player
+// does not hold starts a hold, the first kill with the weapon during the hold
+// is timed and a death ends every hold of the player. Only the stats of the
+// players kept by keep are added, nil keeps every player.
+func (s *WeaponSummary) AddGame(game *parser.Game, keep PlayerFilter) {
+	shown := keptNames(game, keep)
+	var timeline []timelineEntry
+	for i := range game.Items {
+		if t, err := parser.ParseTime(game.Items[i].Time); err == nil {
+			timeline = append(timeline, timelineEntry{time: t, item: &game.Items[i]})
+		}
+	}
+	for i := range game.Kills {
+		if t, err := parser.ParseTime(game.Kills[i].Time); err == nil {
+			timeline = append(timeline, timelineEntry{time: t, kill: &game.Kills[i]})
+		}
+	}
+	// pickups go first on the same second, the weapon is needed for the kill
+	sort.SliceStable(timeline, func(i, j int) bool {
+		if timeline[i].time != timeline[j].time {
+			return timeline[i].time < timeline[j].time
+		}
+		return timeline[i].item != nil && timeline[j].item == nil
+	})
+
+	// holds has the pickup time of the weapons held by each player, only
+	// until the first kill with them
+	holds := make(map[string]map[string]time.Duration)
+	held := make(map[string]map[string]bool)
+	endHolds := func(player string) {
+		delete(holds, player)
+		delete(held, player)
+	}
+	for _, entry := range timeline {
+		if item := entry.item; item != nil {
+			if item.Username == "" || !shown(item.Username) {
+				continue
+			}
+			if family, ok := ammoFamily[item.Item]; ok {
+				s.get(item.Username, family).AmmoPickups++
+				continue
+			}
+			family, ok := strings.CutPrefix(item.Item, "weapon_")
+			if !ok {
+				continue
+			}
+			s.get(item.Username, family).WeaponPickups++
+			if held[item.Username] == nil {
+				held[item.Username] = make(map[string]bool)
+				holds[item.Username] = make(map[string]time.Duration)
+			}
+			if !held[item.Username][family] {
+				held[item.Username][family] = true
+				holds[item.Username][family] = entry.time
+			}
+			continue
+		}
+
+		kill := entry.kill
+		if kill.Killer != kill.Victim && kill.KillerId != 0 && !kill.TeamKill && shown(kill.Killer) {
+			m, _ := parser.LookupMeans(kill.Means)
+			if m.Class == parser.MCWeapon {
+				ws := s.get(kill.Killer, m.Family)
+				ws.Kills++
+				if strings.HasSuffix(m.Name, "_SPLASH") {
+					ws.SplashKills++
+				} else {
+					ws.DirectKills++
+				}
+				if pickedAt, ok := holds[kill.Killer][m.Family]; ok {
+					ws.PickupsWithKill++
+					ws.timeToFirstKill += entry.time - pickedAt
+					delete(holds[kill.Killer], m.Family)
+				}
+			}
+		}
+		endHolds(kill.Victim)
+	}
+
+	addAccuracy := func(info *parser.PlayersInfo) {
+		if !shown(info.Username) {
+			return
+		}
+		for family, a := range info.Weapons {
+			if a.Shots > 0 || a.Hits > 0 {
+				ws := s.get(info.Username, family)
+				ws.Shots += a.Shots
+				ws.Hits += a.Hits
+			}
+		}
+	}
+	for _, info := range game.PlayersInfoById {
+		addAccuracy(info)
+	}
+	for _, info := range game.DisconnectedPlayers {
+		addAccuracy(info)
+	}
+
+	for _, byWeapon := range s.stats {
+		for _, ws := range byWeapon {
+			ws.ratios()
+		}
+	}
+}
+
+// Merge adds the stats of other into s.
+func (s *WeaponSummary) Merge(other *WeaponSummary) {
+	for player, byWeapon := range other.stats {
+		for weapon, ws := range byWeapon {
+			s.get(player, weapon).add(ws)
+		}
+	}
+}
+
+// Stats returns the stats ordered by player and weapon. Empty player or
+// weapon match every one.
+func (s *WeaponSummary) Stats(player string, weapon string) []*WeaponStats {
+	var stats []*WeaponStats
+	for p, byWeapon := range s.stats {
+		if player != "" && p != player {
+			continue
+		}
+		for w, ws := range byWeapon {
+			if weapon == "" || w == weapon {
+				stats = append(stats, ws)
+			}
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Player != stats[j].Player {
+			return stats[i].Player < stats[j].Player
+		}
+		return stats[i].Weapon < stats[j].Weapon
+	})
+	return stats
+}
+
+func PrintHumanReadableWeaponStats(stats []*WeaponStats) {
+	fmt.Println("-------------------- weapons --------------------")
+	player := ""
+	for _, ws := range stats {
+		if ws.Player != player {
+			player = ws.Player
+			fmt.Println(" ", player)
+		}
+		fmt.Printf("    %s: %d kills, %d pickups (%d ammo), %.2f kills per pickup, %.0f%% splash, %.1fs to first kill",
+			ws.Weapon, ws.Kills, ws.WeaponPickups, ws.AmmoPickups, ws.KillsPerPickup, ws.SplashShare*100, ws.AvgSecondsToFirstKill)
+		if ws.Shots > 0 {
+			fmt.Printf(", %d/%d hits (%.0f%% accuracy)", ws.Hits, ws.Shots, ws.Accuracy*100)
+		}
+		fmt.Println()
+	}
+}
+
+func PrintWeaponStatsJson(stats []*WeaponStats) {
+	jsonData, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("could not format json weapon stats. err: %s", err))
+		return
+	}
+	fmt.Println(string(jsonData))
+}
@@ -0,0 +1,87 @@
+package reports
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+func TestWeaponSummary(t *testing.T) {
+	input := `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:02 Item: 2 weapon_railgun
+  0:02 Item: 2 ammo_slugs
+  0:04 Item: 2 weapon_rocketlauncher
+  0:06 Kill: 2 3 10: Isgalamido killed Zeh by MOD_RAILGUN
+  0:07 Kill: 2 3 7: Isgalamido killed Zeh by MOD_ROCKET_SPLASH
+  0:08 Kill: 2 3 6: Isgalamido killed Zeh by MOD_ROCKET
+  0:09 Kill: 1022 2 22: <world> killed Isgalamido by MOD_TRIGGER_HURT
+  0:10 Item: 2 weapon_railgun
+  0:20 ShutdownGame:
+`
+	gs := parser.InitScanner(bufio.NewScanner(strings.NewReader(input)))
+	game, _, err := gs.GetGame()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	summary := NewWeaponSummary()
+	summary.AddGame(game, nil)
+
+	stats := summary.Stats("Isgalamido", "")
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats of 2 weapons, but got %d", len(stats))
+	}
+	rail, rocket := stats[0], stats[1]
+	if rail.Weapon != "railgun" || rail.WeaponPickups != 2 || rail.AmmoPickups != 1 || rail.Kills != 1 ||
+		rail.KillsPerPickup != 0.5 || rail.PickupsWithKill != 1 || rail.AvgSecondsToFirstKill != 4 {
+		t.Errorf("Unexpected railgun stats %+v", rail)
+	}
+	if rocket.Weapon != "rocketlauncher" || rocket.Kills != 2 || rocket.SplashKills != 1 || rocket.SplashShare != 0.5 ||
+		rocket.AvgSecondsToFirstKill != 3 {
+		t.Errorf("Unexpected rocketlauncher stats %+v", rocket)
+	}
+
+	total := NewWeaponSummary()
+	total.Merge(summary)
+	total.Merge(summary)
+	rail = total.Stats("Isgalamido", "railgun")[0]
+	if rail.Kills != 2 || rail.WeaponPickups != 4 || rail.KillsPerPickup != 0.5 || rail.AvgSecondsToFirstKill != 4 {
+		t.Errorf("Unexpected merged railgun stats %+v", rail)
+	}
+}
+
+func TestWeaponSummaryAccuracy(t *testing.T) {
+	input := `  0:00 InitGame: \gamename\osp\g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:06 Kill: 2 2 10: Isgalamido killed Isgalamido by MOD_RAILGUN
+  5:00 Weapon_Stats: 2 Railgun:3:4:0:1 Given:300 Recvd:100
+  5:00 ShutdownGame:
+`
+	gs := parser.InitScanner(bufio.NewScanner(strings.NewReader(input)))
+	game, _, err := gs.GetGame()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	summary := NewWeaponSummary()
+	summary.AddGame(game, nil)
+	summary.AddGame(game, nil)
+
+	rail := summary.Stats("Isgalamido", "railgun")[0]
+	if rail.Shots != 8 || rail.Hits != 6 || rail.Accuracy != 0.75 {
+		t.Errorf("Unexpected railgun stats %+v", rail)
+	}
+
+	accuracy, damage := getAccuracyTables(game, allPlayers)
+	if len(accuracy) != 1 || accuracy[0].Accuracy != 0.75 {
+		t.Errorf("Unexpected accuracy table %+v", accuracy)
+	}
+	if len(damage) != 1 || damage[0].Given != 300 || damage[0].Received != 100 {
+		t.Errorf("Unexpected damage table %+v", damage)
+	}
+}
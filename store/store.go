@@ -0,0 +1,311 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+const (
+	indexFile = "index.jsonl"
+	gamesDir  = "games"
+)
+
+// Meta is the information about a game that is not on the log. Quake logs
+// only have the time elapsed since the game started, so PlayedAt must come
+// from elsewhere, such as the modification time of the log.
+type Meta struct {
+	PlayedAt time.Time
+	Source   string
+}
+
+type PlayerStats struct {
+	Name         string
+	Placement    int
+	Score        int
+	KillCount    int
+	DeathCount   int
+	SuicideCount int
+}
+
+// GameSummary is the entry of a game on the index of the store, it has what
+// is needed to answer queries without loading the whole game.
+type GameSummary struct {
+	Hash            string
+	PlayedAt        time.Time
+	Source          string
+	Map             string
+	GameType        int
+	EndingReason    string
+	EndingCondition parser.EndingCondition
+	Winner          string `json:",omitempty"`
+	WinningTeam     string `json:",omitempty"`
+	TotalKills      int
+	Tags            parser.GameTag `json:",omitempty"`
+	Players         []PlayerStats
+}
+
+// StoredGame is a game as persisted on the store.
+type StoredGame struct {
+	GameSummary
+	Game *parser.Game
+}
+
+// Store keeps the ingested games on a directory: the index is a json line
+// per game and each game is saved on its own file named by its hash. Only
+// one process should write to a store at a time.
+type Store struct {
+	dir    string
+	mu     sync.RWMutex
+	index  []GameSummary
+	byHash map[string]int
+}
+
+// Open loads the store on dir, creating it when it does not exist.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, gamesDir), 0o755); err != nil {
+		return nil, err
+	}
+	s := &Store{
+		dir:    dir,
+		byHash: make(map[string]int),
+	}
+
+	file, err := os.Open(filepath.Join(dir, indexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var summary GameSummary
+		if err := json.Unmarshal(scanner.Bytes(), &summary); err != nil {
+			return nil, fmt.Errorf("corrupted index entry: %w", err)
+		}
+		s.add(summary)
+	}
+	return s, scanner.Err()
+}
+
+func (s *Store) add(summary GameSummary) {
+	s.byHash[summary.Hash] = len(s.index)
+	s.index = append(s.index, summary)
+}
+
+// Ingest saves a game unless a game with the same content hash was already
+// ingested. It returns the hash of the game and if it was saved.
+func (s *Store) Ingest(game *parser.Game, meta Meta) (string, bool, error) {
+	hash := game.Hash()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byHash[hash]; ok {
+		return hash, false, nil
+	}
+
+	stored := StoredGame{
+		GameSummary: summarize(hash, game, meta),
+		Game:        game,
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return hash, false, err
+	}
+	gamePath := filepath.Join(s.dir, gamesDir, hash+".json")
+	if err := os.WriteFile(gamePath, data, 0o644); err != nil {
+		return hash, false, err
+	}
+
+	// the index is written last so a failure never leaves an entry without its game
+	line, err := json.Marshal(stored.GameSummary)
+	if err != nil {
+		return hash, false, err
+	}
+	index, err := os.OpenFile(filepath.Join(s.dir, indexFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return hash, false, err
+	}
+	defer index.Close()
+	if _, err := index.Write(append(line, '\n')); err != nil {
+		return hash, false, err
+	}
+
+	s.add(stored.GameSummary)
+	return hash, true, nil
+}
+
+func summarize(hash string, game *parser.Game, meta Meta) GameSummary {
+	gameType, _ := strconv.Atoi(game.ServerConfig["g_gametype"])
+	result := game.Result()
+	summary := GameSummary{
+		Hash:            hash,
+		PlayedAt:        meta.PlayedAt,
+		Source:          meta.Source,
+		Map:             game.ServerConfig["mapname"],
+		GameType:        gameType,
+		EndingReason:    game.EndingReason,
+		EndingCondition: result.Condition,
+		Winner:          result.Winner,
+		WinningTeam:     result.WinningTeam,
+		TotalKills:      game.TotalKills,
+		Tags:            game.Tags,
+	}
+	placeByName := make(map[string]int, len(result.Placements))
+	for _, p := range result.Placements {
+		placeByName[p.Name] = p.Place
+	}
+	// a player that disconnected and connected again has an entry for each
+	// connection, their stats are merged by name
+	byName := make(map[string]*PlayerStats)
+	add := func(pi *parser.PlayersInfo) {
+		if pi.Id == 0 || pi.Id == parser.UnknownPlayerId || pi.Username == "" {
+			return
+		}
+		ps, ok := byName[pi.Username]
+		if !ok {
+			ps = &PlayerStats{Name: pi.Username, Placement: placeByName[pi.Username]}
+			byName[pi.Username] = ps
+		}
+		ps.Score += pi.Score
+		ps.KillCount += pi.KillCount
+		ps.DeathCount += pi.DeathCount
+		ps.SuicideCount += pi.SuicideCount
+	}
+	for _, pi := range game.PlayersInfoById {
+		add(pi)
+	}
+	for _, pi := range game.DisconnectedPlayers {
+		add(pi)
+	}
+	for _, ps := range byName {
+		summary.Players = append(summary.Players, *ps)
+	}
+	sort.Slice(summary.Players, func(i, j int) bool {
+		return summary.Players[i].Name < summary.Players[j].Name
+	})
+	return summary
+}
+
+// Filter selects games on queries, empty fields match every game.
+type Filter struct {
+	Map    string
+	Player string
+	Since  time.Time
+	Until  time.Time
+	// Exclude skips the games with any of these tags.
+	Exclude parser.GameTag
+}
+
+func (f Filter) matches(summary *GameSummary) bool {
+	if f.Map != "" && f.Map != summary.Map {
+		return false
+	}
+	if summary.Tags.Any(f.Exclude) {
+		return false
+	}
+	if !f.Since.IsZero() && summary.PlayedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !summary.PlayedAt.Before(f.Until) {
+		return false
+	}
+	if f.Player != "" {
+		if _, ok := summary.player(f.Player); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (gs *GameSummary) player(name string) (PlayerStats, bool) {
+	for _, ps := range gs.Players {
+		if ps.Name == name {
+			return ps, true
+		}
+	}
+	return PlayerStats{}, false
+}
+
+// Games returns the summaries of the games matching the filter ordered by the
+// time they were played.
+func (s *Store) Games(f Filter) []GameSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var games []GameSummary
+	for i := range s.index {
+		if f.matches(&s.index[i]) {
+			games = append(games, s.index[i])
+		}
+	}
+	sort.SliceStable(games, func(i, j int) bool {
+		return games[i].PlayedAt.Before(games[j].PlayedAt)
+	})
+	return games
+}
+
+// Game loads a stored game by its hash.
+func (s *Store) Game(hash string) (*StoredGame, error) {
+	s.mu.RLock()
+	_, ok := s.byHash[hash]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("game not found: %s", hash)
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, gamesDir, hash+".json"))
+	if err != nil {
+		return nil, err
+	}
+	stored := &StoredGame{}
+	if err := json.Unmarshal(data, stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// PlayerPoint is the performance of a player on a game.
+type PlayerPoint struct {
+	PlayedAt time.Time
+	Hash     string
+	Map      string
+	PlayerStats
+	// KillDeathRatio is the kills over deaths, or the kills when the player did not die.
+	KillDeathRatio float64
+}
+
+// PlayerHistory returns the performance of a player on each game matching
+// the filter, ordered by the time they were played.
+func (s *Store) PlayerHistory(name string, f Filter) []PlayerPoint {
+	f.Player = name
+	var history []PlayerPoint
+	for _, summary := range s.Games(f) {
+		ps, _ := summary.player(name)
+		point := PlayerPoint{
+			PlayedAt:       summary.PlayedAt,
+			Hash:           summary.Hash,
+			Map:            summary.Map,
+			PlayerStats:    ps,
+			KillDeathRatio: float64(ps.KillCount),
+		}
+		if ps.DeathCount > 0 {
+			point.KillDeathRatio = float64(ps.KillCount) / float64(ps.DeathCount)
+		}
+		history = append(history, point)
+	}
+	return history
+}
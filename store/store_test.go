@@ -0,0 +1,158 @@
+package store
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedroegsilva/cw-test/parser"
+)
+
+func readGames(t *testing.T) []*parser.Game {
+	t.Helper()
+	file, err := os.Open("../input/qgames.log")
+	if err != nil {
+		t.Fatalf("could not open sample log: %s", err)
+	}
+	defer file.Close()
+
+	var games []*parser.Game
+	gs := parser.InitScanner(bufio.NewScanner(file))
+	for game, ok, err := gs.GetGame(); ok; game, ok, err = gs.GetGame() {
+		if err == nil {
+			games = append(games, game)
+		}
+	}
+	return games
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	games := readGames(t)
+	september := time.Date(2026, 9, 10, 0, 0, 0, 0, time.UTC)
+	october := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	for i, game := range games {
+		playedAt := september
+		if i%2 == 1 {
+			playedAt = october
+		}
+		if _, inserted, err := s.Ingest(game, Meta{PlayedAt: playedAt, Source: "qgames.log"}); err != nil || !inserted {
+			t.Fatalf("Expected game %d to be inserted, got %v err %v", i, inserted, err)
+		}
+	}
+
+	// a store opened again must deduplicate the games already ingested
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for i, game := range games {
+		if _, inserted, err := s.Ingest(game, Meta{PlayedAt: october}); err != nil || inserted {
+			t.Fatalf("Expected game %d to be deduplicated, got %v err %v", i, inserted, err)
+		}
+	}
+	if all := s.Games(Filter{}); len(all) != len(games) {
+		t.Fatalf("Expected %d games, got %d", len(games), len(all))
+	}
+
+	september15 := time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC)
+	for _, summary := range s.Games(Filter{Map: "q3dm17", Until: september15}) {
+		if summary.Map != "q3dm17" || !summary.PlayedAt.Equal(september) {
+			t.Errorf("Unexpected game on filter %+v", summary)
+		}
+	}
+
+	history := s.PlayerHistory("Zeh", Filter{Since: september15})
+	if len(history) == 0 {
+		t.Fatalf("Expected history of Zeh")
+	}
+	for _, point := range history {
+		if !point.PlayedAt.Equal(october) || point.Name != "Zeh" {
+			t.Errorf("Unexpected history point %+v", point)
+		}
+		if point.DeathCount > 0 && point.KillDeathRatio != float64(point.KillCount)/float64(point.DeathCount) {
+			t.Errorf("Unexpected K/D on %+v", point)
+		}
+	}
+
+	stored, err := s.Game(history[0].Hash)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if stored.Game.Hash() != history[0].Hash || len(stored.Game.Kills) != stored.TotalKills {
+		t.Errorf("Expected stored game to keep its content, got %+v", stored.GameSummary)
+	}
+}
+
+func TestStoreExclude(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	games := readGames(t)
+	tagged := 0
+	for _, game := range games {
+		if game.Tags.Any(parser.GTNoKills) {
+			tagged++
+		}
+		if _, _, err := s.Ingest(game, Meta{}); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	if tagged == 0 {
+		t.Fatalf("Expected the sample log to have games without kills")
+	}
+
+	kept := s.Games(Filter{Exclude: parser.GTNoKills})
+	if len(kept) != len(games)-tagged {
+		t.Errorf("Expected %d games, but got %d", len(games)-tagged, len(kept))
+	}
+	for _, summary := range kept {
+		if summary.Tags.Any(parser.GTNoKills) || summary.TotalKills == 0 {
+			t.Errorf("Expected games without kills to be excluded, got %+v", summary)
+		}
+	}
+}
+
+func TestStoreDisconnectedPlayers(t *testing.T) {
+	input := `  0:00 InitGame: \g_gametype\0\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:05 Kill: 3 2 10: Zeh killed Isgalamido by MOD_RAILGUN
+  0:06 ClientDisconnect: 3
+  0:07 ClientConnect: 3
+  0:07 ClientUserinfoChanged: 3 n\Zeh\t\0
+  0:08 Kill: 3 2 10: Zeh killed Isgalamido by MOD_RAILGUN
+  0:09 ClientDisconnect: 3
+  1:10 ShutdownGame:
+`
+	gs := parser.InitScanner(bufio.NewScanner(strings.NewReader(input)))
+	game, _, err := gs.GetGame()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, _, err := s.Ingest(game, Meta{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	history := s.PlayerHistory("Zeh", Filter{})
+	if len(history) != 1 || history[0].KillCount != 2 {
+		t.Fatalf("Expected the 2 kills of Zeh after disconnecting, but got %+v", history)
+	}
+	if players := s.Games(Filter{})[0].Players; len(players) != 2 {
+		t.Errorf("Expected 2 players, but got %+v", players)
+	}
+}
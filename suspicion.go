@@ -0,0 +1,161 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/pedroegsilva/cw-test/batch"
+	"github.com/pedroegsilva/cw-test/parser"
+	"github.com/pedroegsilva/cw-test/reports"
+	"github.com/pedroegsilva/cw-test/store"
+	"github.com/rs/zerolog/log"
+)
+
+// namedGame is a game with the name used on the reports and the players
+// checked.
+type namedGame struct {
+	game *parser.Game
+	name string
+	keep reports.PlayerFilter
+}
+
+// runSuspicion prints the players whose stats are statistical outliers, with
+// an explanation of each flag for the admins to review.
+func runSuspicion(args []string) int {
+	fs := flag.NewFlagSet("suspicion", flag.ExitOnError)
+	var inputPaths pathList
+	fs.Var(&inputPaths, "i", "full path of the log file, can be repeated. Extra arguments are also read as log files")
+	storeDir := fs.String("store", "", "directory of the stats store, the past games of the players are used as their history")
+	playedAtFlag := fs.String("played-at", "", "date the games were played (YYYY-MM-DD or RFC3339), only the stored games played before are history. Defaults to the log modification time")
+	mode := fs.String("mode", "lenient", "error policy: strict, lenient or recover")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of log files parsed concurrently")
+	defaults := reports.DefaultSuspicionOptions()
+	killRate := fs.Float64("max-kill-rate", defaults.MaxKillsPerMinute, "kills per minute from which a player is flagged")
+	railgunShare := fs.Float64("max-railgun-share", defaults.MaxRailgunShare, "share of the weapon kills with the railgun from which a player is flagged")
+	killInterval := fs.Duration("min-kill-interval", defaults.MinKillInterval, "shortest time expected between two kills with different weapons, the log times have a resolution of a second")
+	highPing := fs.Int("high-ping", defaults.HighPing, "ping from which leading the kills is flagged")
+	deviations := fs.Float64("max-deviations", defaults.MaxHistoryDeviations, "standard deviations above the history of the player from which a game is flagged")
+	readTagFlags := tagFlags(fs)
+	readDialect := dialectFlag(fs)
+	readWhere := whereFlag(fs)
+	fs.Parse(args)
+	inputPaths = append(inputPaths, fs.Args()...)
+
+	if len(inputPaths) == 0 {
+		fs.Usage()
+		return 2
+	}
+	policy, err := parser.ParseErrorPolicy(*mode)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	tagOptions, exclude, err := readTagFlags()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	dialect, err := readDialect()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	where, err := readWhere()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	var playedAt time.Time
+	if *playedAtFlag != "" {
+		if playedAt, err = parseDate(*playedAtFlag); err != nil {
+			log.Error().Msg(err.Error())
+			return 2
+		}
+	}
+	var statsStore *store.Store
+	if *storeDir != "" {
+		if statsStore, err = store.Open(*storeDir); err != nil {
+			log.Error().Msg(fmt.Sprintf("could not open store: %s", err))
+			return 1
+		}
+	}
+
+	var games []namedGame
+	// until is when the first game checked was played, the history is made
+	// of the games played before it
+	until := playedAt
+	code := 0
+	opts := batch.Options{Workers: *workers, ErrorPolicy: policy, TagOptions: tagOptions, Exclude: exclude, Dialect: dialect}
+	batch.ProcessFiles(inputPaths, opts, func(result *batch.FileResult) {
+		for idx, gr := range result.Games {
+			name := gameName(result.Path, result.FirstGame+idx+1, len(inputPaths) > 1)
+			if gr.Err != nil {
+				log.Error().Msg(fmt.Sprintf("error on %s: %s", name, gr.Err))
+				continue
+			}
+			if !gr.Game.Tags.Any(exclude) && (where == nil || where.MatchGame(gr.Game)) {
+				games = append(games, namedGame{gr.Game, name, batch.KeepPlayers(where, gr.Game)})
+			}
+		}
+		if *playedAtFlag == "" && len(result.Games) > 0 {
+			if t := modTime(result.Path); until.IsZero() || t.Before(until) {
+				until = t
+			}
+		}
+		if result.Err != nil {
+			log.Error().Msg(fmt.Sprintf("aborting %s: %s", result.Path, result.Err))
+			code = 1
+		}
+	})
+
+	suspicionOpts := defaults
+	suspicionOpts.MaxKillsPerMinute = *killRate
+	suspicionOpts.MaxRailgunShare = *railgunShare
+	suspicionOpts.MinKillInterval = *killInterval
+	suspicionOpts.HighPing = *highPing
+	suspicionOpts.MaxHistoryDeviations = *deviations
+	report := reports.NewSuspicionReport(suspicionOpts)
+	if statsStore != nil {
+		seedHistory(report, statsStore, games, store.Filter{Exclude: exclude, Until: until})
+	}
+	for _, g := range games {
+		report.AddGame(g.game, g.name, g.keep)
+	}
+
+	suspicions := report.Suspicions()
+	if os.Getenv("OUT_HUMAN") != "" {
+		reports.PrintHumanReadableSuspicions(suspicions)
+	} else {
+		reports.PrintSuspicionsJson(suspicions)
+	}
+	return code
+}
+
+// seedHistory adds the stored games of the players matching the filter as
+// their history, leaving out the games being checked when they were already
+// ingested.
+func seedHistory(report *reports.SuspicionReport, statsStore *store.Store, games []namedGame, f store.Filter) {
+	checked := make(map[string]bool, len(games))
+	players := make(map[string]bool)
+	for _, g := range games {
+		checked[g.game.Hash()] = true
+		for _, info := range g.game.PlayersInfoById {
+			players[info.Username] = true
+		}
+		for _, info := range g.game.DisconnectedPlayers {
+			players[info.Username] = true
+		}
+	}
+	for player := range players {
+		var ratios []float64
+		for _, point := range statsStore.PlayerHistory(player, f) {
+			if !checked[point.Hash] {
+				ratios = append(ratios, point.KillDeathRatio)
+			}
+		}
+		report.SeedHistory(player, ratios)
+	}
+}
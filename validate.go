@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pedroegsilva/cw-test/parser"
+	"github.com/rs/zerolog/log"
+)
+
+type threshold struct {
+	name  string
+	max   *int
+	value func(*parser.HealthReport) int
+}
+
+type validateOutput struct {
+	*parser.HealthReport
+	ThresholdsExceeded []string
+}
+
+// runValidate prints the health report of a log as json and returns the exit
+// code. The code is 1 when any configured threshold is exceeded.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	inputPath := fs.String("i", "", "full path of the log file")
+	thresholds := []threshold{
+		{name: "unknown-headers", value: func(hr *parser.HealthReport) int { return hr.CountByHeader[parser.LHUnknown.String()] }},
+		{name: "syntax-errors", value: func(hr *parser.HealthReport) int { return hr.SyntaxErrors }},
+		{name: "context-errors", value: func(hr *parser.HealthReport) int { return hr.ContextErrors }},
+		{name: "without-shutdown", value: func(hr *parser.HealthReport) int { return hr.GamesWithoutShutdown }},
+		{name: "unexpected-shutdowns", value: func(hr *parser.HealthReport) int { return hr.UnexpectedShutdownGames }},
+		{name: "duplicate-init", value: func(hr *parser.HealthReport) int { return hr.DuplicateInitGames }},
+		{name: "time-regressions", value: func(hr *parser.HealthReport) int { return hr.TimeRegressions }},
+	}
+	for i := range thresholds {
+		thresholds[i].max = fs.Int("max-"+thresholds[i].name, -1, fmt.Sprintf("maximum accepted %s, -1 disables the check", thresholds[i].name))
+	}
+	fs.Parse(args)
+
+	if *inputPath == "" {
+		fs.Usage()
+		return 2
+	}
+
+	file, err := os.Open(*inputPath)
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("Error opening file: %s", err))
+		return 2
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), parser.DefaultMaxLineSize)
+	// the report of the lines read before an error is still printed
+	code := 0
+	report, err := parser.CheckHealth(scanner)
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("error reading file: %s", err))
+		code = 2
+	}
+
+	out := validateOutput{HealthReport: report, ThresholdsExceeded: []string{}}
+	for _, t := range thresholds {
+		if *t.max >= 0 && t.value(report) > *t.max {
+			out.ThresholdsExceeded = append(out.ThresholdsExceeded, fmt.Sprintf("%s: %d > %d", t.name, t.value(report), *t.max))
+		}
+	}
+
+	jsonData, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("could not format json health report: %s", err))
+		return 2
+	}
+	fmt.Println(string(jsonData))
+
+	if code == 0 && len(out.ThresholdsExceeded) > 0 {
+		code = 1
+	}
+	return code
+}
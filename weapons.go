@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/pedroegsilva/cw-test/batch"
+	"github.com/pedroegsilva/cw-test/parser"
+	"github.com/pedroegsilva/cw-test/reports"
+	"github.com/rs/zerolog/log"
+)
+
+// runWeapons prints the efficiency of each player with each weapon family,
+// aggregated across the games of the logs.
+func runWeapons(args []string) int {
+	fs := flag.NewFlagSet("weapons", flag.ExitOnError)
+	var inputPaths pathList
+	fs.Var(&inputPaths, "i", "full path of the log file, can be repeated. Extra arguments are also read as log files")
+	player := fs.String("player", "", "only the stats of this player")
+	weapon := fs.String("weapon", "", "only the stats of this weapon family, e.g. railgun or rocketlauncher")
+	mode := fs.String("mode", "lenient", "error policy: strict, lenient or recover")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of log files parsed concurrently")
+	readTagFlags := tagFlags(fs)
+	readDialect := dialectFlag(fs)
+	readWhere := whereFlag(fs)
+	fs.Parse(args)
+	inputPaths = append(inputPaths, fs.Args()...)
+
+	if len(inputPaths) == 0 {
+		fs.Usage()
+		return 2
+	}
+	policy, err := parser.ParseErrorPolicy(*mode)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	tagOptions, exclude, err := readTagFlags()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	dialect, err := readDialect()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+	where, err := readWhere()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return 2
+	}
+
+	summary := reports.NewWeaponSummary()
+	code := 0
+	opts := batch.Options{Workers: *workers, ErrorPolicy: policy, TagOptions: tagOptions, Exclude: exclude, Dialect: dialect}
+	batch.ProcessFiles(inputPaths, opts, func(result *batch.FileResult) {
+		for idx, gr := range result.Games {
+			if gr.Err != nil {
+				log.Error().Msg(fmt.Sprintf("error on %s: %s", gameName(result.Path, idx+1, len(inputPaths) > 1), gr.Err))
+				continue
+			}
+			if !gr.Game.Tags.Any(exclude) && (where == nil || where.MatchGame(gr.Game)) {
+				summary.AddGame(gr.Game, batch.KeepPlayers(where, gr.Game))
+			}
+		}
+		if result.Err != nil {
+			log.Error().Msg(fmt.Sprintf("aborting %s: %s", result.Path, result.Err))
+			code = 1
+		}
+	})
+
+	stats := summary.Stats(*player, *weapon)
+	if os.Getenv("OUT_HUMAN") != "" {
+		reports.PrintHumanReadableWeaponStats(stats)
+	} else {
+		reports.PrintWeaponStatsJson(stats)
+	}
+	return code
+}